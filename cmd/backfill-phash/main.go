@@ -0,0 +1,73 @@
+// backfill-phash computes the perceptual hash of the images that were uploaded before it was introduced
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/mbeka02/image-service/config"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/imgstore"
+)
+
+const batchSize = 100
+
+func main() {
+	conf, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("unable to load config: %v", err)
+	}
+	store, err := database.NewStore(conf.DB_URI)
+	if err != nil {
+		log.Fatalf("...unable to setup the db : %v", err)
+	}
+	fileStorage, err := imgstore.NewGCStorage(conf.GCLOUD_PROJECT_ID, conf.GCLOUD_BUCKET_NAME)
+	if err != nil {
+		log.Fatalf("...unable to setup cloud storage:%v", err)
+	}
+
+	ctx := context.Background()
+	var lastImageId int64
+	var updated, skipped int
+	for {
+		images, err := store.GetImagesWithoutPhash(ctx, database.GetImagesWithoutPhashParams{
+			ImageID: lastImageId,
+			Limit:   batchSize,
+		})
+		if err != nil {
+			log.Fatalf("unable to get images:%v", err)
+		}
+		for _, image := range images {
+			// images that can't be hashed keep a null phash , paging by id makes sure they are not retried
+			lastImageId = image.ImageID
+			hash, err := hashImage(ctx, fileStorage, image)
+			if err != nil {
+				log.Printf("skipping image %d:%v", image.ImageID, err)
+				skipped++
+				continue
+			}
+			if err := store.SetImagePhash(ctx, database.SetImagePhashParams{
+				ImageID: image.ImageID,
+				Phash:   sql.NullInt64{Int64: int64(hash), Valid: true},
+			}); err != nil {
+				log.Fatalf("unable to update image %d:%v", image.ImageID, err)
+			}
+			updated++
+		}
+		if len(images) < batchSize {
+			break
+		}
+	}
+	log.Printf("backfill finished , %d images updated and %d skipped", updated, skipped)
+}
+
+func hashImage(ctx context.Context, fileStorage imgstore.Storage, image database.Image) (uint64, error) {
+	reader, err := fileStorage.Download(ctx, image.FileName)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return imgproc.PerceptualHash(reader)
+}
@@ -5,20 +5,27 @@ import (
 	"log"
 	"net/http"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mbeka02/image-service/internal/auth"
 	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
 	"github.com/mbeka02/image-service/internal/imgproc"
 	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/mbeka02/image-service/internal/jobs"
 	"github.com/mbeka02/image-service/internal/mailer"
 	"github.com/mbeka02/image-service/internal/server"
+	"github.com/mbeka02/image-service/internal/trash"
+	"github.com/mbeka02/image-service/internal/uploads"
+	"github.com/mbeka02/image-service/internal/webhook"
 
 	"github.com/mbeka02/image-service/config"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, stopBackground context.CancelFunc, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -36,6 +43,9 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 		log.Printf("server forced to shutdown with error: %v\n", err)
 	}
 
+	// stop the background workers , a job that is interrupted goes back to the queue without using up an attempt
+	stopBackground()
+
 	log.Println("server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
@@ -51,19 +61,90 @@ func main() {
 	if err != nil {
 		log.Fatalf("...unable to setup the db : %v", err)
 	}
-	maker, err := auth.NewJWTMaker(conf.SYMMETRIC_KEY)
+	maker, err := auth.NewMaker(conf.TOKEN_FORMAT, conf.SYMMETRIC_KEY, conf.TOKEN_KEYS_DIR, conf.TOKEN_KEY_ID)
 	if err != nil {
 		log.Fatalf("...unable to setup up the auth token maker:%v", err)
 	}
-	newMailer := mailer.NewMailer(conf.MAILER_HOST, conf.MAILER_PASSWORD)
+	newMailer := mailer.NewMailer(conf.MAILER_HOST, conf.MAILER_PASSWORD, conf.MAILER_FROM)
 	fileStorage, err := imgstore.NewGCStorage(conf.GCLOUD_PROJECT_ID, conf.GCLOUD_BUCKET_NAME)
 	if err != nil {
 		log.Fatalf("...unable to setup cloud storage:%v", err)
 	}
 	newImageProcessor := imgproc.NewBimgProcessor(100, 0)
+	allowedNetworks, err := fetch.ParseNetworks(strings.Split(conf.IMPORT_ALLOWED_NETWORKS, ","))
+	if err != nil {
+		log.Fatalf("...invalid IMPORT_ALLOWED_NETWORKS:%v", err)
+	}
+	webhookNetworks, err := fetch.ParseNetworks(strings.Split(conf.WEBHOOK_ALLOWED_NETWORKS, ","))
+	if err != nil {
+		log.Fatalf("...invalid WEBHOOK_ALLOWED_NETWORKS:%v", err)
+	}
+	webhookDispatcher := webhook.NewDispatcher(store, webhookNetworks)
+	fetcher := fetch.NewFetcher(fetch.Options{
+		Timeout:         30 * time.Second,
+		MaxBytes:        25 << 20,
+		MaxRedirects:    3,
+		AllowedNetworks: allowedNetworks,
+	})
+	// verification links can't be built from the host of the request since clients control it
+	if err := fetch.CheckURL(conf.APP_URL); err != nil {
+		log.Fatalf("...invalid APP_URL:%v", err)
+	}
+	gpsPolicy, err := imgproc.ParseGPSPolicy(conf.GPS_POLICY)
+	if err != nil {
+		log.Fatalf("...invalid GPS_POLICY:%v", err)
+	}
 	done := make(chan bool, 1)
-	server := server.NewServer(":"+conf.PORT, store, maker, conf.ACCESS_TOKEN_DURATION, newMailer, fileStorage, newImageProcessor)
-	go gracefulShutdown(server, done)
+	server := server.NewServer(server.Config{
+		Addr:                 ":" + conf.PORT,
+		Store:                store,
+		AuthMaker:            maker,
+		AccessTokenDuration:  conf.ACCESS_TOKEN_DURATION,
+		RefreshTokenDuration: conf.REFRESH_TOKEN_DURATION,
+		Mailer:               newMailer,
+		FileStorage:          fileStorage,
+		ImageProcessor:       newImageProcessor,
+		Webhooks:             webhookDispatcher,
+		Fetcher:              fetcher,
+		PresignExpiry:        conf.PRESIGN_URL_TTL,
+		DownloadExpiry:       conf.DOWNLOAD_URL_TTL,
+		JobMaxAttempts:       conf.JOB_MAX_ATTEMPTS,
+		GPSPolicy:            gpsPolicy,
+		TrashRetention:       conf.TRASH_RETENTION,
+		MaxVersions:          conf.MAX_IMAGE_VERSIONS,
+		AppURL:               conf.APP_URL,
+		VerificationTTL:      conf.EMAIL_VERIFICATION_TTL,
+		RequireVerifiedEmail: conf.REQUIRE_VERIFIED_EMAIL,
+	})
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	jobPool := jobs.NewPool(store, fileStorage, newImageProcessor, webhookDispatcher, conf.WORKER_COUNT)
+	uploadCleaner := uploads.NewCleaner(store, fileStorage)
+	trashPurger := trash.NewPurger(store, fileStorage, conf.TRASH_RETENTION)
+	background.Add(4)
+	go func() {
+		defer background.Done()
+		jobPool.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		webhookDispatcher.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		uploadCleaner.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		trashPurger.Run(backgroundCtx)
+	}()
+
+	go gracefulShutdown(server, stopBackground, done)
 	log.Println("the server is listening on port:" + conf.PORT)
-	server.ListenAndServe()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("...unable to start the server:%v", err)
+	}
+	<-done
+	background.Wait()
 }
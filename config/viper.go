@@ -7,14 +7,31 @@ import (
 )
 
 type Config struct {
-	DB_URI                string        `mapstructure:"DB_URI"`
-	SYMMETRIC_KEY         string        `mapstructure:"SYMMETRIC_KEY"`
-	ACCESS_TOKEN_DURATION time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	PORT                  string        `mapstructure:"PORT"`
-	MAILER_PASSWORD       string        `mapstructure:"MAILER_PASSWORD"`
-	MAILER_HOST           string        `mapstructure:"MAILER_HOST"`
-	GCLOUD_PROJECT_ID     string        `mapstructure:"GCLOUD_PROJECT_ID"`
-	GCLOUD_BUCKET_NAME    string        `mapstructure:"GCLOUD_BUCKET_NAME"`
+	DB_URI                   string        `mapstructure:"DB_URI"`
+	SYMMETRIC_KEY            string        `mapstructure:"SYMMETRIC_KEY"`
+	TOKEN_FORMAT             string        `mapstructure:"TOKEN_FORMAT"`
+	TOKEN_KEYS_DIR           string        `mapstructure:"TOKEN_KEYS_DIR"`
+	TOKEN_KEY_ID             string        `mapstructure:"TOKEN_KEY_ID"`
+	ACCESS_TOKEN_DURATION    time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	REFRESH_TOKEN_DURATION   time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	PORT                     string        `mapstructure:"PORT"`
+	MAILER_PASSWORD          string        `mapstructure:"MAILER_PASSWORD"`
+	MAILER_HOST              string        `mapstructure:"MAILER_HOST"`
+	MAILER_FROM              string        `mapstructure:"MAILER_FROM"`
+	APP_URL                  string        `mapstructure:"APP_URL"`
+	EMAIL_VERIFICATION_TTL   time.Duration `mapstructure:"EMAIL_VERIFICATION_TTL"`
+	REQUIRE_VERIFIED_EMAIL   bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	GCLOUD_PROJECT_ID        string        `mapstructure:"GCLOUD_PROJECT_ID"`
+	GCLOUD_BUCKET_NAME       string        `mapstructure:"GCLOUD_BUCKET_NAME"`
+	WORKER_COUNT             int           `mapstructure:"WORKER_COUNT"`
+	JOB_MAX_ATTEMPTS         int           `mapstructure:"JOB_MAX_ATTEMPTS"`
+	IMPORT_ALLOWED_NETWORKS  string        `mapstructure:"IMPORT_ALLOWED_NETWORKS"`
+	WEBHOOK_ALLOWED_NETWORKS string        `mapstructure:"WEBHOOK_ALLOWED_NETWORKS"`
+	PRESIGN_URL_TTL          time.Duration `mapstructure:"PRESIGN_URL_TTL"`
+	DOWNLOAD_URL_TTL         time.Duration `mapstructure:"DOWNLOAD_URL_TTL"`
+	GPS_POLICY               string        `mapstructure:"GPS_POLICY"`
+	TRASH_RETENTION          time.Duration `mapstructure:"TRASH_RETENTION"`
+	MAX_IMAGE_VERSIONS       int           `mapstructure:"MAX_IMAGE_VERSIONS"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -27,11 +44,26 @@ func LoadConfig(path string) (*Config, error) {
 	viper.AutomaticEnv()
 
 	for _, key := range []string{
-		"DB_URI", "SYMMETRIC_KEY", "ACCESS_TOKEN_DURATION", "PORT",
+		"DB_URI", "SYMMETRIC_KEY", "ACCESS_TOKEN_DURATION", "REFRESH_TOKEN_DURATION", "PORT",
+		"TOKEN_FORMAT", "TOKEN_KEYS_DIR", "TOKEN_KEY_ID",
 		"MAILER_PASSWORD", "MAILER_HOST", "GCLOUD_PROJECT_ID", "GCLOUD_BUCKET_NAME",
+		"MAILER_FROM", "APP_URL", "EMAIL_VERIFICATION_TTL", "REQUIRE_VERIFIED_EMAIL",
+		"WORKER_COUNT", "JOB_MAX_ATTEMPTS", "IMPORT_ALLOWED_NETWORKS", "WEBHOOK_ALLOWED_NETWORKS",
+		"PRESIGN_URL_TTL", "DOWNLOAD_URL_TTL", "GPS_POLICY", "TRASH_RETENTION", "MAX_IMAGE_VERSIONS",
 	} {
 		viper.BindEnv(key)
 	}
+	viper.SetDefault("TOKEN_FORMAT", "jwt")
+	viper.SetDefault("REFRESH_TOKEN_DURATION", 7*24*time.Hour)
+	viper.SetDefault("MAILER_FROM", "hello@demomailtrap.com")
+	viper.SetDefault("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+	viper.SetDefault("WORKER_COUNT", 4)
+	viper.SetDefault("JOB_MAX_ATTEMPTS", 5)
+	viper.SetDefault("PRESIGN_URL_TTL", 15*time.Minute)
+	viper.SetDefault("DOWNLOAD_URL_TTL", 15*time.Minute)
+	viper.SetDefault("GPS_POLICY", "redact")
+	viper.SetDefault("TRASH_RETENTION", 30*24*time.Hour)
+	viper.SetDefault("MAX_IMAGE_VERSIONS", 10)
 
 	// Try reading .env but don't care if it doesn't exist
 	_ = viper.ReadInConfig()
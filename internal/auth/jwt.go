@@ -30,25 +30,28 @@ func NewJWTMaker(secret string) (Maker, error) {
 }
 
 // This method is used to create a new JWT token , it implements the Maker interface
-func (maker *JWTMaker) Create(email string, userId int64, duration time.Duration) (string, error) {
-	payload := NewPayload(email, userId, duration)
+func (maker *JWTMaker) Create(email string, userId, orgId int64, duration time.Duration) (string, error) {
+	payload := NewPayload(email, userId, orgId, duration)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 	return token.SignedString([]byte(maker.secret))
 }
 
 // This method is used to verify a new JWT token , it implements the Maker interface
-
 func (maker *JWTMaker) Verify(tokenString string) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		// check signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
 		return []byte(maker.secret), nil
 	}
-	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, keyFunc)
+	return verifyJWT(tokenString, keyFunc, jwt.SigningMethodHS256)
+}
+
+// verifyJWT checks the signature with the key keyFunc returns and the registered claims , tokens signed with another method are rejected
+func verifyJWT(tokenString string, keyFunc jwt.Keyfunc, method jwt.SigningMethod) (*Payload, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, keyFunc, jwt.WithValidMethods([]string{method.Alg()}), jwt.WithExpirationRequired())
 	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
 		return nil, ErrInvalidToken
 	}
 
@@ -57,10 +60,5 @@ func (maker *JWTMaker) Verify(tokenString string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	// check if the token has expired
-	if time.Now().After(claims.ExpiresAt) {
-		return nil, ErrExpiredToken
-	}
-
 	return claims, nil
 }
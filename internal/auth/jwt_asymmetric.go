@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AsymmetricJWTMaker signs tokens with the current key of the set and puts its id in the kid header
+type AsymmetricJWTMaker struct {
+	keys   *KeySet
+	method jwt.SigningMethod
+}
+
+// NewAsymmetricJWTMaker creates a maker for EdDSA or RS256 , every key in the set has to be of the matching type
+func NewAsymmetricJWTMaker(keys *KeySet, algorithm string) (Maker, error) {
+	var method jwt.SigningMethod
+	for kid, key := range keys.keys {
+		switch algorithm {
+		case "EdDSA":
+			method = jwt.SigningMethodEdDSA
+			if _, ok := key.(ed25519.PrivateKey); !ok {
+				return nil, fmt.Errorf("the key %s is not an Ed25519 key", kid)
+			}
+		case "RS256":
+			method = jwt.SigningMethodRS256
+			if _, ok := key.(*rsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("the key %s is not an RSA key", kid)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported JWT algorithm:%s", algorithm)
+		}
+	}
+	return &AsymmetricJWTMaker{
+		keys:   keys,
+		method: method,
+	}, nil
+}
+
+func (maker *AsymmetricJWTMaker) Create(email string, userId, orgId int64, duration time.Duration) (string, error) {
+	payload := NewPayload(email, userId, orgId, duration)
+
+	token := jwt.NewWithClaims(maker.method, payload)
+	token.Header["kid"] = maker.keys.CurrentKID
+	return token.SignedString(maker.keys.current())
+}
+
+func (maker *AsymmetricJWTMaker) Verify(tokenString string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("the token has no kid header")
+		}
+		key, ok := maker.keys.key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %s", kid)
+		}
+		return key.Public(), nil
+	}
+	return verifyJWT(tokenString, keyFunc, maker.method)
+}
+
+func (maker *AsymmetricJWTMaker) JWKS() JWKS {
+	return maker.keys.JWKS()
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testEmail  = "jane@example.com"
+	testUserID = 7
+	testOrgID  = 3
+)
+
+func mustCreate(t *testing.T, maker Maker, duration time.Duration) string {
+	t.Helper()
+	token, err := maker.Create(testEmail, testUserID, testOrgID, duration)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return token
+}
+
+func checkPayload(t *testing.T, payload *Payload) {
+	t.Helper()
+	if payload.Email != testEmail || payload.UserID != testUserID || payload.OrgID != testOrgID {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if !payload.ExpiresAt.After(payload.IssuedAt) {
+		t.Errorf("the token expires at %v , before it was issued at %v", payload.ExpiresAt, payload.IssuedAt)
+	}
+}
+
+func mustAsymmetricJWTMaker(t *testing.T, dir, currentKid, algorithm string) Maker {
+	t.Helper()
+	maker, err := NewAsymmetricJWTMaker(mustLoadKeySet(t, dir, currentKid), algorithm)
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+	return maker
+}
+
+// signJWT signs the payload with any method and key , the makers must only accept what they signed themselves
+func signJWT(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, NewPayload(testEmail, testUserID, testOrgID, time.Minute))
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestAsymmetricJWTMaker(t *testing.T) {
+	for _, algorithm := range []string{"EdDSA", "RS256"} {
+		t.Run(algorithm, func(t *testing.T) {
+			dir := newTestKeyDir(t, algorithm, "old", "new")
+			keys := mustLoadKeySet(t, dir, "new")
+			maker := mustAsymmetricJWTMaker(t, dir, "new", algorithm)
+			signedByOld := mustCreate(t, mustAsymmetricJWTMaker(t, dir, "old", algorithm), time.Minute)
+
+			// a key that isn't in the set , once with an id the set doesn't have and once with the id of the current key
+			otherKey := newTestKey(t, algorithm)
+			otherAlgorithm := map[string]string{"EdDSA": "RS256", "RS256": "EdDSA"}[algorithm]
+			otherAlgorithmKey := newTestKey(t, otherAlgorithm)
+			otherMethod := jwt.GetSigningMethod(otherAlgorithm)
+			method := jwt.GetSigningMethod(algorithm)
+
+			publicDER, err := x509.MarshalPKIXPublicKey(keys.current().Public())
+			if err != nil {
+				t.Fatalf("marshal public key: %v", err)
+			}
+			publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+			valid := mustCreate(t, maker, time.Minute)
+			// the claims of another user with the signature of the valid token
+			forOtherUser, err := maker.Create(testEmail, testUserID+1, testOrgID, time.Minute)
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			parts := strings.Split(valid, ".")
+			tampered := parts[0] + "." + strings.Split(forOtherUser, ".")[1] + "." + parts[2]
+
+			tests := []struct {
+				name    string
+				token   string
+				wantErr error
+			}{
+				{"round trip", valid, nil},
+				{"retired key", signedByOld, nil},
+				{"expired", mustCreate(t, maker, -time.Minute), ErrExpiredToken},
+				{"unknown kid", signJWT(t, method, otherKey, "other"), ErrInvalidToken},
+				{"no kid", signJWT(t, method, keys.current(), ""), ErrInvalidToken},
+				{"current kid with another key", signJWT(t, method, otherKey, "new"), ErrInvalidToken},
+				{"alg none", signJWT(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "new"), ErrInvalidToken},
+				{"hs256 with the public key", signJWT(t, jwt.SigningMethodHS256, publicPEM, "new"), ErrInvalidToken},
+				{"hs256 with the raw public key", signJWT(t, jwt.SigningMethodHS256, publicDER, "new"), ErrInvalidToken},
+				{"other algorithm", signJWT(t, otherMethod, otherAlgorithmKey, "new"), ErrInvalidToken},
+				{"tampered payload", tampered, ErrInvalidToken},
+				{"garbage", "not.a.token", ErrInvalidToken},
+			}
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					payload, err := maker.Verify(test.token)
+					if !errors.Is(err, test.wantErr) {
+						t.Fatalf("got %v , want %v", err, test.wantErr)
+					}
+					if err == nil {
+						checkPayload(t, payload)
+					}
+				})
+			}
+
+			// the key is rotated out once its file is removed , the tokens it signed stop working
+			if err := os.Remove(filepath.Join(dir, "old.pem")); err != nil {
+				t.Fatalf("remove key: %v", err)
+			}
+			rotated := mustAsymmetricJWTMaker(t, dir, "new", algorithm)
+			if _, err := rotated.Verify(signedByOld); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("rotated out key: got %v , want %v", err, ErrInvalidToken)
+			}
+			if _, err := rotated.Verify(valid); err != nil {
+				t.Errorf("current key after the rotation: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewAsymmetricJWTMaker(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyType   string
+		algorithm string
+	}{
+		{"rsa key for EdDSA", "RS256", "EdDSA"},
+		{"ed25519 key for RS256", "EdDSA", "RS256"},
+		{"unsupported algorithm", "EdDSA", "ES256"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := newTestKeyDir(t, test.keyType, "key")
+			if _, err := NewAsymmetricJWTMaker(mustLoadKeySet(t, dir, "key"), test.algorithm); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
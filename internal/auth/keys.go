@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeySet holds the private keys tokens are signed with , keys that were rotated out stay in the set so that the tokens they signed can still be verified
+type KeySet struct {
+	// CurrentKID is the id of the key new tokens are signed with
+	CurrentKID string
+	keys       map[string]crypto.Signer
+}
+
+// LoadKeySet reads every <kid>.pem file in dir , the files hold PKCS#8 (or PKCS#1 for RSA) private keys
+func LoadKeySet(dir, currentKid string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the keys:%v", err)
+	}
+	keys := make(map[string]crypto.Signer, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the key %s:%v", path, err)
+		}
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s:%v", path, err)
+		}
+		keys[strings.TrimSuffix(filepath.Base(path), ".pem")] = key
+	}
+	if _, ok := keys[currentKid]; !ok {
+		return nil, fmt.Errorf("there is no key with the id %q in %s", currentKid, dir)
+	}
+	return &KeySet{CurrentKID: currentKid, keys: keys}, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T , only Ed25519 and RSA keys are supported", key)
+}
+
+func (ks *KeySet) current() crypto.Signer {
+	return ks.keys[ks.CurrentKID]
+}
+
+func (ks *KeySet) key(kid string) (crypto.Signer, bool) {
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// JWK is the public part of a key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// Ed25519 keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyPublisher is implemented by the makers that sign with asymmetric keys , other services verify our tokens with the published keys
+type KeyPublisher interface {
+	JWKS() JWKS
+}
+
+// JWKS returns the public keys of the set , sorted by id
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for kid, key := range ks.keys {
+		switch public := key.Public().(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256", N: base64.RawURLEncoding.EncodeToString(public.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
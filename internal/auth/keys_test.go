@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestKey generates a key of the type the algorithm signs with , EdDSA and PASETO use Ed25519 and RS256 uses RSA
+func newTestKey(t *testing.T, algorithm string) crypto.Signer {
+	t.Helper()
+	if algorithm == "RS256" {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("generate rsa key: %v", err)
+		}
+		return key
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	return key
+}
+
+// writeTestKey stores the key as <kid>.pem in dir like the keys LoadKeySet reads
+func writeTestKey(t *testing.T, dir, kid string, key crypto.Signer) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+}
+
+// newTestKeyDir writes a new key for every kid into a temporary directory
+func newTestKeyDir(t *testing.T, algorithm string, kids ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, kid := range kids {
+		writeTestKey(t, dir, kid, newTestKey(t, algorithm))
+	}
+	return dir
+}
+
+func mustLoadKeySet(t *testing.T, dir, currentKid string) *KeySet {
+	t.Helper()
+	keys, err := LoadKeySet(dir, currentKid)
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+	return keys
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := newTestKeyDir(t, "EdDSA", "2024-01", "2024-06")
+	// PKCS#1 is what openssl genrsa writes
+	rsaKey := newTestKey(t, "RS256").(*rsa.PrivateKey)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if err := os.WriteFile(filepath.Join(dir, "legacy.pem"), pkcs1, 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	// only .pem files are keys
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	keys := mustLoadKeySet(t, dir, "2024-06")
+	if keys.CurrentKID != "2024-06" || len(keys.keys) != 3 {
+		t.Fatalf("got current %q and %d keys , want 2024-06 and 3", keys.CurrentKID, len(keys.keys))
+	}
+	if _, ok := keys.key("legacy"); !ok {
+		t.Error("the PKCS#1 key wasn't loaded")
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ecdsa key: %v", err)
+	}
+	ecdsaDER, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	ecdsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecdsaDER})
+
+	tests := []struct {
+		name       string
+		files      map[string][]byte
+		currentKid string
+	}{
+		{"missing current key", nil, "2024-06"},
+		{"not pem", map[string][]byte{"bad": []byte("not a key")}, "bad"},
+		{"unsupported key type", map[string][]byte{"ecdsa": ecdsaPEM}, "ecdsa"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for kid, data := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+					t.Fatalf("write key: %v", err)
+				}
+			}
+			if _, err := LoadKeySet(dir, test.currentKid); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	retired := newTestKey(t, "EdDSA")
+	current := newTestKey(t, "EdDSA")
+	rsaKey := newTestKey(t, "RS256")
+	writeTestKey(t, dir, "2024-01", retired)
+	writeTestKey(t, dir, "2024-06", current)
+	writeTestKey(t, dir, "rsa", rsaKey)
+
+	rsaPublic := rsaKey.Public().(*rsa.PublicKey)
+	want := JWKS{Keys: []JWK{
+		{Kty: "OKP", Kid: "2024-01", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(retired.Public().(ed25519.PublicKey))},
+		{Kty: "OKP", Kid: "2024-06", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(current.Public().(ed25519.PublicKey))},
+		{Kty: "RSA", Kid: "rsa", Use: "sig", Alg: "RS256", N: base64.RawURLEncoding.EncodeToString(rsaPublic.N.Bytes()), E: "AQAB"},
+	}}
+	if got := mustLoadKeySet(t, dir, "2024-06").JWKS(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v , want %+v", got, want)
+	}
+
+	// the makers publish the same set , that is what /.well-known/jwks.json serves
+	edDir := newTestKeyDir(t, "EdDSA", "old", "new")
+	keys := mustLoadKeySet(t, edDir, "new")
+	for _, format := range []string{MakerJWTEdDSA, MakerPasetoPublic} {
+		maker, err := NewMaker(format, "", edDir, "new")
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		publisher, ok := maker.(KeyPublisher)
+		if !ok {
+			t.Fatalf("%s doesn't publish its keys", format)
+		}
+		if got := publisher.JWKS(); !reflect.DeepEqual(got, keys.JWKS()) || len(got.Keys) != 2 {
+			t.Errorf("%s: got %+v , want the retired and the active key", format, got)
+		}
+	}
+}
@@ -1,8 +1,43 @@
 package auth
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Maker interface {
-	Create(username string, userId int64, duration time.Duration) (string, error)
+	Create(username string, userId, orgId int64, duration time.Duration) (string, error)
 	Verify(tokenString string) (*Payload, error)
 }
+
+// the token formats NewMaker can create
+const (
+	MakerJWT          = "jwt"
+	MakerJWTEdDSA     = "jwt-eddsa"
+	MakerJWTRS256     = "jwt-rs256"
+	MakerPasetoLocal  = "paseto-local"
+	MakerPasetoPublic = "paseto-public"
+)
+
+// NewMaker creates the maker for the token format , the symmetric formats use the secret and the asymmetric ones the keys in keysDir
+func NewMaker(format, secret, keysDir, currentKid string) (Maker, error) {
+	switch format {
+	case MakerJWT:
+		return NewJWTMaker(secret)
+	case MakerPasetoLocal:
+		return NewPasetoLocalMaker(secret)
+	case MakerJWTEdDSA, MakerJWTRS256, MakerPasetoPublic:
+		keys, err := LoadKeySet(keysDir, currentKid)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case MakerJWTEdDSA:
+			return NewAsymmetricJWTMaker(keys, "EdDSA")
+		case MakerJWTRS256:
+			return NewAsymmetricJWTMaker(keys, "RS256")
+		}
+		return NewPasetoPublicMaker(keys)
+	}
+	return nil, fmt.Errorf("unsupported token format:%s", format)
+}
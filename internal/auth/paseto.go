@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoLocalMaker creates v4.local tokens , the payload is encrypted so clients can't read it
+type PasetoLocalMaker struct {
+	key paseto.V4SymmetricKey
+}
+
+func NewPasetoLocalMaker(secret string) (Maker, error) {
+	if len(secret) < minimumSecretLength {
+		return nil, fmt.Errorf("invalid secret length , it must be atleast 32 characters")
+	}
+	// v4.local keys are exactly 32 bytes , the secret is hashed so that any secret of the minimum length can be used
+	sum := sha256.Sum256([]byte(secret))
+	key, err := paseto.V4SymmetricKeyFromBytes(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return &PasetoLocalMaker{key: key}, nil
+}
+
+func (maker *PasetoLocalMaker) Create(email string, userId, orgId int64, duration time.Duration) (string, error) {
+	token, err := newPasetoToken(NewPayload(email, userId, orgId, duration))
+	if err != nil {
+		return "", err
+	}
+	return token.V4Encrypt(maker.key, nil), nil
+}
+
+func (maker *PasetoLocalMaker) Verify(tokenString string) (*Payload, error) {
+	token, err := paseto.NewParser().ParseV4Local(maker.key, tokenString, nil)
+	if err != nil {
+		return nil, pasetoError(err)
+	}
+	return pasetoPayload(token)
+}
+
+// PasetoPublicMaker creates v4.public tokens signed with the current Ed25519 key of the set , the key id is in the footer
+type PasetoPublicMaker struct {
+	keys *KeySet
+}
+
+type pasetoFooter struct {
+	Kid string `json:"kid"`
+}
+
+func NewPasetoPublicMaker(keys *KeySet) (Maker, error) {
+	for kid, key := range keys.keys {
+		if _, ok := key.(ed25519.PrivateKey); !ok {
+			return nil, fmt.Errorf("the key %s is not an Ed25519 key", kid)
+		}
+	}
+	return &PasetoPublicMaker{keys: keys}, nil
+}
+
+func (maker *PasetoPublicMaker) Create(email string, userId, orgId int64, duration time.Duration) (string, error) {
+	token, err := newPasetoToken(NewPayload(email, userId, orgId, duration))
+	if err != nil {
+		return "", err
+	}
+	footer, err := json.Marshal(pasetoFooter{Kid: maker.keys.CurrentKID})
+	if err != nil {
+		return "", err
+	}
+	token.SetFooter(footer)
+	key, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(maker.keys.current().(ed25519.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+	return token.V4Sign(key, nil), nil
+}
+
+func (maker *PasetoPublicMaker) Verify(tokenString string) (*Payload, error) {
+	parser := paseto.NewParser()
+	// the footer is only trusted to pick the key , the signature covers it
+	rawFooter, err := parser.UnsafeParseFooter(paseto.V4Public, tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	footer := pasetoFooter{}
+	if err := json.Unmarshal(rawFooter, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, ok := maker.keys.key(footer.Kid)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	publicKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	token, err := parser.ParseV4Public(publicKey, tokenString, nil)
+	if err != nil {
+		return nil, pasetoError(err)
+	}
+	return pasetoPayload(token)
+}
+
+func (maker *PasetoPublicMaker) JWKS() JWKS {
+	return maker.keys.JWKS()
+}
+
+func newPasetoToken(payload *Payload) (paseto.Token, error) {
+	token := paseto.NewToken()
+	token.SetIssuedAt(payload.IssuedAt)
+	token.SetExpiration(payload.ExpiresAt)
+	token.SetString("email", payload.Email)
+	if err := token.Set("user_id", payload.UserID); err != nil {
+		return paseto.Token{}, err
+	}
+	if err := token.Set("org_id", payload.OrgID); err != nil {
+		return paseto.Token{}, err
+	}
+	return token, nil
+}
+
+func pasetoPayload(token *paseto.Token) (*Payload, error) {
+	payload := &Payload{}
+	var err error
+	if payload.Email, err = token.GetString("email"); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := token.Get("user_id", &payload.UserID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := token.Get("org_id", &payload.OrgID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if payload.IssuedAt, err = token.GetIssuedAt(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if payload.ExpiresAt, err = token.GetExpiration(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return payload, nil
+}
+
+// pasetoError maps the parser errors , the only rule the parser checks is the expiry
+func pasetoError(err error) error {
+	if errors.Is(err, paseto.RuleError{}) {
+		return ErrExpiredToken
+	}
+	return ErrInvalidToken
+}
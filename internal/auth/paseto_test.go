@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func mustPasetoPublicMaker(t *testing.T, dir, currentKid string) Maker {
+	t.Helper()
+	maker, err := NewPasetoPublicMaker(mustLoadKeySet(t, dir, currentKid))
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+	return maker
+}
+
+// signPaseto signs a v4.public token with the key and footer , the makers must only accept what they signed themselves
+func signPaseto(t *testing.T, key ed25519.PrivateKey, footer string) string {
+	t.Helper()
+	token, err := newPasetoToken(NewPayload(testEmail, testUserID, testOrgID, time.Minute))
+	if err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+	if footer != "" {
+		token.SetFooter([]byte(footer))
+	}
+	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(key)
+	if err != nil {
+		t.Fatalf("secret key: %v", err)
+	}
+	return token.V4Sign(secretKey, nil)
+}
+
+func TestPasetoLocalMaker(t *testing.T) {
+	if _, err := NewPasetoLocalMaker("too short"); err == nil {
+		t.Error("expected an error for a short secret")
+	}
+	maker, err := NewPasetoLocalMaker(testSecret)
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+	otherMaker, err := NewPasetoLocalMaker(strings.ToUpper(testSecret))
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+	jwtMaker, err := NewJWTMaker(testSecret)
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+	publicMaker := mustPasetoPublicMaker(t, newTestKeyDir(t, "EdDSA", "key"), "key")
+
+	valid := mustCreate(t, maker, time.Minute)
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"round trip", valid, nil},
+		{"expired", mustCreate(t, maker, -time.Minute), ErrExpiredToken},
+		{"other secret", mustCreate(t, otherMaker, time.Minute), ErrInvalidToken},
+		{"v4.public token", mustCreate(t, publicMaker, time.Minute), ErrInvalidToken},
+		{"jwt with the same secret", mustCreate(t, jwtMaker, time.Minute), ErrInvalidToken},
+		{"tampered", valid[:len(valid)-2] + "AA", ErrInvalidToken},
+		{"garbage", "v4.local.garbage", ErrInvalidToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := maker.Verify(test.token)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got %v , want %v", err, test.wantErr)
+			}
+			if err == nil {
+				checkPayload(t, payload)
+			}
+		})
+	}
+	// the payload of a local token is encrypted
+	if strings.Contains(valid, "jane") {
+		t.Error("the email can be read from the token")
+	}
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	dir := newTestKeyDir(t, "EdDSA", "old", "new")
+	keys := mustLoadKeySet(t, dir, "new")
+	current := keys.current().(ed25519.PrivateKey)
+	maker := mustPasetoPublicMaker(t, dir, "new")
+	signedByOld := mustCreate(t, mustPasetoPublicMaker(t, dir, "old"), time.Minute)
+	otherKey := newTestKey(t, "EdDSA").(ed25519.PrivateKey)
+	localMaker, err := NewPasetoLocalMaker(testSecret)
+	if err != nil {
+		t.Fatalf("new maker: %v", err)
+	}
+
+	valid := mustCreate(t, maker, time.Minute)
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"round trip", valid, nil},
+		{"retired key", signedByOld, nil},
+		{"expired", mustCreate(t, maker, -time.Minute), ErrExpiredToken},
+		{"unknown kid", signPaseto(t, otherKey, `{"kid":"other"}`), ErrInvalidToken},
+		{"no footer", signPaseto(t, current, ""), ErrInvalidToken},
+		{"current kid with another key", signPaseto(t, otherKey, `{"kid":"new"}`), ErrInvalidToken},
+		{"kid swapped in the footer", strings.TrimSuffix(signedByOld, signedByOld[strings.LastIndex(signedByOld, "."):]) + valid[strings.LastIndex(valid, "."):], ErrInvalidToken},
+		{"v4.local token", mustCreate(t, localMaker, time.Minute), ErrInvalidToken},
+		{"garbage", "v4.public.garbage", ErrInvalidToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := maker.Verify(test.token)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got %v , want %v", err, test.wantErr)
+			}
+			if err == nil {
+				checkPayload(t, payload)
+			}
+		})
+	}
+
+	// the key is rotated out once its file is removed , the tokens it signed stop working
+	if err := os.Remove(filepath.Join(dir, "old.pem")); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	rotated := mustPasetoPublicMaker(t, dir, "new")
+	if _, err := rotated.Verify(signedByOld); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("rotated out key: got %v , want %v", err, ErrInvalidToken)
+	}
+	if _, err := rotated.Verify(valid); err != nil {
+		t.Errorf("current key after the rotation: %v", err)
+	}
+
+	if _, err := NewPasetoPublicMaker(mustLoadKeySet(t, newTestKeyDir(t, "RS256", "rsa"), "rsa")); err == nil {
+		t.Error("expected an error for an RSA key")
+	}
+}
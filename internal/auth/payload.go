@@ -11,14 +11,24 @@ type Payload struct {
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 	UserID    int64     `json:"user_id"`
+	// OrgID is the organization the user is working in , lists and uploads are scoped to it
+	OrgID int64 `json:"org_id"`
 	jwt.RegisteredClaims
 }
 
-func NewPayload(email string, userId int64, duration time.Duration) *Payload {
+func NewPayload(email string, userId, orgId int64, duration time.Duration) *Payload {
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(duration)
 	return &Payload{
 		UserID:    userId,
+		OrgID:     orgId,
 		Email:     email,
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(duration),
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+		// the JWT makers validate the expiry with the registered claims
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	}
 }
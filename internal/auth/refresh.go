@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// refresh tokens are the session id and a random secret , only the hash of the secret is stored
+
+// NewRefreshToken returns a refresh token for the session and the hash of its secret
+func NewRefreshToken(sessionId string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("unable to generate a refresh token:%v", err)
+	}
+	encoded := hex.EncodeToString(secret)
+	return sessionId + "." + encoded, HashRefreshSecret(encoded), nil
+}
+
+// ParseRefreshToken splits a refresh token into the session id and the hash of its secret
+func ParseRefreshToken(token string) (string, string, error) {
+	sessionId, secret, ok := strings.Cut(token, ".")
+	if !ok || sessionId == "" || secret == "" {
+		return "", "", ErrInvalidToken
+	}
+	return sessionId, HashRefreshSecret(secret), nil
+}
+
+func HashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// SameRefreshHash compares the hashes in constant time
+func SameRefreshHash(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
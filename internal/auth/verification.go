@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewVerificationToken returns a one-time email verification token and its hash , only the hash is stored
+func NewVerificationToken() (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("unable to generate a verification token:%v", err)
+	}
+	token := hex.EncodeToString(secret)
+	return token, HashVerificationToken(token), nil
+}
+
+func HashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,70 @@
+package blobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgstore"
+)
+
+// Acquire takes a reference to the content of an object uploaded to the organization , when the organization already stores the same content the upload is deleted and the stored object is returned instead
+func Acquire(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, orgId int64, upload *imgstore.UploadResponse) (*imgstore.UploadResponse, error) {
+	blob, err := store.AcquireBlob(ctx, database.AcquireBlobParams{
+		OrgID:       orgId,
+		ContentHash: upload.ContentHash,
+		FileName:    upload.FileName,
+		FileSize:    upload.Size,
+		StorageUrl:  upload.StorageUrl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to save the blob:%v", err)
+	}
+	if blob.FileName == upload.FileName {
+		return upload, nil
+	}
+	if err := fileStorage.Delete(ctx, upload.FileName); err != nil {
+		log.Printf("unable to delete the duplicate object %s:%v", upload.FileName, err)
+	}
+	return &imgstore.UploadResponse{
+		FileName:    blob.FileName,
+		StorageUrl:  blob.StorageUrl,
+		Size:        blob.FileSize,
+		ContentHash: blob.ContentHash,
+	}, nil
+}
+
+// Release drops a reference the organization holds to the content , the object is deleted together with the last reference
+func Release(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, orgId int64, contentHash string) error {
+	blob, err := store.ReleaseBlob(ctx, database.ReleaseBlobParams{
+		OrgID:       orgId,
+		ContentHash: contentHash,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to release the blob:%v", err)
+	}
+	if blob.RefCount > 0 {
+		return nil
+	}
+	deleted, err := store.DeleteUnreferencedBlob(ctx, database.DeleteUnreferencedBlobParams{
+		OrgID:       orgId,
+		ContentHash: contentHash,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete the blob:%v", err)
+	}
+	// someone acquired the blob again after it was released
+	if deleted == 0 {
+		return nil
+	}
+	shared, err := store.CountBlobsByFileName(ctx, blob.FileName)
+	if err != nil {
+		return fmt.Errorf("unable to check the blob object:%v", err)
+	}
+	// the object is still used by the blob of another organization
+	if shared > 0 {
+		return nil
+	}
+	return fileStorage.Delete(ctx, blob.FileName)
+}
@@ -7,59 +7,192 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/sqlc-dev/pqtype"
 )
 
-const createImage = `-- name: CreateImage :one
-INSERT INTO images(user_id , file_name , file_size , storage_url , metadata) VALUES ($1,$2,$3,$4,$5)RETURNING file_name , file_size , storage_url
+const countOrgImages = `-- name: CountOrgImages :one
+SELECT count(*) FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
 `
 
-type CreateImageParams struct {
-	UserID     int64
-	FileName   string
-	FileSize   int64
-	StorageUrl string
-	Metadata   pqtype.NullRawMessage
+type CountOrgImagesParams struct {
+	OrgID         int64
+	ContentType   sql.NullString
+	MinWidth      sql.NullInt32
+	MaxWidth      sql.NullInt32
+	MinHeight     sql.NullInt32
+	MaxHeight     sql.NullInt32
+	MinSize       sql.NullInt64
+	MaxSize       sql.NullInt64
+	CreatedAfter  sql.NullTime
+	CreatedBefore sql.NullTime
+	Name          sql.NullString
+	Tag           sql.NullString
+	Attributes    pqtype.NullRawMessage
+	AlbumID       sql.NullInt64
+}
+
+// takes the same filters as the ListOrgImagesBy queries
+func (q *Queries) CountOrgImages(ctx context.Context, arg CountOrgImagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOrgImages,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
-type CreateImageRow struct {
-	FileName   string
-	FileSize   int64
-	StorageUrl string
+const createImage = `-- name: CreateImage :one
+INSERT INTO images(user_id , org_id , file_name , file_size , storage_url , metadata , content_hash , phash) VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token
+`
+
+type CreateImageParams struct {
+	UserID      int64
+	OrgID       int64
+	FileName    string
+	FileSize    int64
+	StorageUrl  string
+	Metadata    pqtype.NullRawMessage
+	ContentHash sql.NullString
+	Phash       sql.NullInt64
 }
 
-func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (CreateImageRow, error) {
+func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image, error) {
 	row := q.db.QueryRowContext(ctx, createImage,
 		arg.UserID,
+		arg.OrgID,
 		arg.FileName,
 		arg.FileSize,
 		arg.StorageUrl,
 		arg.Metadata,
+		arg.ContentHash,
+		arg.Phash,
+	)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
 	)
-	var i CreateImageRow
-	err := row.Scan(&i.FileName, &i.FileSize, &i.StorageUrl)
 	return i, err
 }
 
-const deleteUserImage = `-- name: DeleteUserImage :exec
-DELETE FROM images WHERE image_id=$1 AND user_id=$2
+const deleteOrgImage = `-- name: DeleteOrgImage :exec
+DELETE FROM images WHERE image_id=$1 AND org_id=$2
 `
 
-type DeleteUserImageParams struct {
+type DeleteOrgImageParams struct {
 	ImageID int64
-	UserID  int64
+	OrgID   int64
 }
 
-func (q *Queries) DeleteUserImage(ctx context.Context, arg DeleteUserImageParams) error {
-	_, err := q.db.ExecContext(ctx, deleteUserImage, arg.ImageID, arg.UserID)
+func (q *Queries) DeleteOrgImage(ctx context.Context, arg DeleteOrgImageParams) error {
+	_, err := q.db.ExecContext(ctx, deleteOrgImage, arg.ImageID, arg.OrgID)
 	return err
 }
 
+const getExpiredTrash = `-- name: GetExpiredTrash :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE deleted_at<$1 ORDER BY deleted_at , image_id LIMIT $2
+`
+
+type GetExpiredTrashParams struct {
+	DeletedAt sql.NullTime
+	Limit     int32
+}
+
+func (q *Queries) GetExpiredTrash(ctx context.Context, arg GetExpiredTrashParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredTrash, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getImage = `-- name: GetImage :one
-SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at FROM images WHERE image_id=$1
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE image_id=$1 AND deleted_at IS NULL
 `
 
+// images in the trash are left out , see GetTrashedImage
 func (q *Queries) GetImage(ctx context.Context, imageID int64) (Image, error) {
 	row := q.db.QueryRowContext(ctx, getImage, imageID)
 	var i Image
@@ -72,22 +205,337 @@ func (q *Queries) GetImage(ctx context.Context, imageID int64) (Image, error) {
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const getImagesWithoutPhash = `-- name: GetImagesWithoutPhash :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE phash IS NULL AND image_id>$1 ORDER BY image_id LIMIT $2
+`
+
+type GetImagesWithoutPhashParams struct {
+	ImageID int64
+	Limit   int32
+}
+
+func (q *Queries) GetImagesWithoutPhash(ctx context.Context, arg GetImagesWithoutPhashParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getImagesWithoutPhash, arg.ImageID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrgImageByHash = `-- name: GetOrgImageByHash :one
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE org_id=$1 AND content_hash=$2 AND deleted_at IS NULL ORDER BY image_id LIMIT 1
+`
+
+type GetOrgImageByHashParams struct {
+	OrgID       int64
+	ContentHash sql.NullString
+}
+
+func (q *Queries) GetOrgImageByHash(ctx context.Context, arg GetOrgImageByHashParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getOrgImageByHash, arg.OrgID, arg.ContentHash)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const getOrgImages = `-- name: GetOrgImages :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE org_id=$1 AND deleted_at IS NULL ORDER BY image_id LIMIT $2 OFFSET $3
+`
+
+type GetOrgImagesParams struct {
+	OrgID  int64
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) GetOrgImages(ctx context.Context, arg GetOrgImagesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getOrgImages, arg.OrgID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublicImages = `-- name: GetPublicImages :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE visibility='public' AND deleted_at IS NULL ORDER BY created_at DESC , image_id DESC LIMIT $1 OFFSET $2
+`
+
+type GetPublicImagesParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) GetPublicImages(ctx context.Context, arg GetPublicImagesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicImages, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSimilarImages = `-- name: GetSimilarImages :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token , length(replace((phash # $1::bigint)::bit(64)::text , '0' , ''))::int AS distance FROM images
+WHERE org_id=$2 AND image_id<>$3 AND deleted_at IS NULL AND phash IS NOT NULL AND length(replace((phash # $1::bigint)::bit(64)::text , '0' , ''))<=$4::int
+ORDER BY distance , image_id LIMIT $5
+`
+
+type GetSimilarImagesParams struct {
+	Phash       int64
+	OrgID       int64
+	ImageID     int64
+	MaxDistance int32
+	MaxResults  int32
+}
+
+type GetSimilarImagesRow struct {
+	ImageID          int64
+	UserID           int64
+	FileName         string
+	FileSize         int64
+	StorageUrl       string
+	Metadata         pqtype.NullRawMessage
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	ContentHash      sql.NullString
+	Phash            sql.NullInt64
+	Attributes       json.RawMessage
+	Visibility       string
+	OrgID            int64
+	DeletedAt        sql.NullTime
+	Version          int32
+	VersionCreatedAt time.Time
+	Transformations  pqtype.NullRawMessage
+	UnlistedToken    sql.NullString
+	Distance         int32
+}
+
+// the distance is the number of bits that differ between the perceptual hashes
+func (q *Queries) GetSimilarImages(ctx context.Context, arg GetSimilarImagesParams) ([]GetSimilarImagesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSimilarImages,
+		arg.Phash,
+		arg.OrgID,
+		arg.ImageID,
+		arg.MaxDistance,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSimilarImagesRow
+	for rows.Next() {
+		var i GetSimilarImagesRow
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+			&i.Distance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrashedImage = `-- name: GetTrashedImage :one
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE image_id=$1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetTrashedImage(ctx context.Context, imageID int64) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getTrashedImage, imageID)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
 	)
 	return i, err
 }
 
-const getUserImages = `-- name: GetUserImages :many
-SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at FROM images WHERE user_id=$1 LIMIT $2 OFFSET $3
+const getTrashedImages = `-- name: GetTrashedImages :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE org_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC , image_id DESC LIMIT $2 OFFSET $3
 `
 
-type GetUserImagesParams struct {
-	UserID int64
+type GetTrashedImagesParams struct {
+	OrgID  int64
 	Limit  int32
 	Offset int32
 }
 
-func (q *Queries) GetUserImages(ctx context.Context, arg GetUserImagesParams) ([]Image, error) {
-	rows, err := q.db.QueryContext(ctx, getUserImages, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) GetTrashedImages(ctx context.Context, arg GetTrashedImagesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, getTrashedImages, arg.OrgID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +552,16 @@ func (q *Queries) GetUserImages(ctx context.Context, arg GetUserImagesParams) ([
 			&i.Metadata,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
 		); err != nil {
 			return nil, err
 		}
@@ -117,3 +575,807 @@ func (q *Queries) GetUserImages(ctx context.Context, arg GetUserImagesParams) ([
 	}
 	return items, nil
 }
+
+const getUnlistedImage = `-- name: GetUnlistedImage :one
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images WHERE unlisted_token=$1 AND visibility='unlisted' AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUnlistedImage(ctx context.Context, unlistedToken sql.NullString) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getUnlistedImage, unlistedToken)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const listOrgImagesByCreatedAtAsc = `-- name: ListOrgImagesByCreatedAtAsc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (created_at , image_id)>($16::timestamptz , $15))
+ORDER BY created_at , image_id
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByCreatedAtAscParams struct {
+	OrgID           int64
+	ContentType     sql.NullString
+	MinWidth        sql.NullInt32
+	MaxWidth        sql.NullInt32
+	MinHeight       sql.NullInt32
+	MaxHeight       sql.NullInt32
+	MinSize         sql.NullInt64
+	MaxSize         sql.NullInt64
+	CreatedAfter    sql.NullTime
+	CreatedBefore   sql.NullTime
+	Name            sql.NullString
+	Tag             sql.NullString
+	Attributes      pqtype.NullRawMessage
+	AlbumID         sql.NullInt64
+	CursorID        sql.NullInt64
+	CursorCreatedAt sql.NullTime
+	PageLimit       int32
+	PageOffset      int32
+}
+
+// sorted by created_at with the image id breaking ties , the cursor is the created_at and id of the last image of the previous page
+func (q *Queries) ListOrgImagesByCreatedAtAsc(ctx context.Context, arg ListOrgImagesByCreatedAtAscParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByCreatedAtAsc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrgImagesByCreatedAtDesc = `-- name: ListOrgImagesByCreatedAtDesc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (created_at , image_id)<($16::timestamptz , $15))
+ORDER BY created_at DESC , image_id DESC
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByCreatedAtDescParams struct {
+	OrgID           int64
+	ContentType     sql.NullString
+	MinWidth        sql.NullInt32
+	MaxWidth        sql.NullInt32
+	MinHeight       sql.NullInt32
+	MaxHeight       sql.NullInt32
+	MinSize         sql.NullInt64
+	MaxSize         sql.NullInt64
+	CreatedAfter    sql.NullTime
+	CreatedBefore   sql.NullTime
+	Name            sql.NullString
+	Tag             sql.NullString
+	Attributes      pqtype.NullRawMessage
+	AlbumID         sql.NullInt64
+	CursorID        sql.NullInt64
+	CursorCreatedAt sql.NullTime
+	PageLimit       int32
+	PageOffset      int32
+}
+
+// see ListOrgImagesByCreatedAtAsc
+func (q *Queries) ListOrgImagesByCreatedAtDesc(ctx context.Context, arg ListOrgImagesByCreatedAtDescParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByCreatedAtDesc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorCreatedAt,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrgImagesByFileSizeAsc = `-- name: ListOrgImagesByFileSizeAsc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (file_size , image_id)>($16::bigint , $15))
+ORDER BY file_size , image_id
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByFileSizeAscParams struct {
+	OrgID          int64
+	ContentType    sql.NullString
+	MinWidth       sql.NullInt32
+	MaxWidth       sql.NullInt32
+	MinHeight      sql.NullInt32
+	MaxHeight      sql.NullInt32
+	MinSize        sql.NullInt64
+	MaxSize        sql.NullInt64
+	CreatedAfter   sql.NullTime
+	CreatedBefore  sql.NullTime
+	Name           sql.NullString
+	Tag            sql.NullString
+	Attributes     pqtype.NullRawMessage
+	AlbumID        sql.NullInt64
+	CursorID       sql.NullInt64
+	CursorFileSize sql.NullInt64
+	PageLimit      int32
+	PageOffset     int32
+}
+
+// sorted by file_size with the image id breaking ties , the cursor is the file_size and id of the last image of the previous page
+func (q *Queries) ListOrgImagesByFileSizeAsc(ctx context.Context, arg ListOrgImagesByFileSizeAscParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByFileSizeAsc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorFileSize,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrgImagesByFileSizeDesc = `-- name: ListOrgImagesByFileSizeDesc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (file_size , image_id)<($16::bigint , $15))
+ORDER BY file_size DESC , image_id DESC
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByFileSizeDescParams struct {
+	OrgID          int64
+	ContentType    sql.NullString
+	MinWidth       sql.NullInt32
+	MaxWidth       sql.NullInt32
+	MinHeight      sql.NullInt32
+	MaxHeight      sql.NullInt32
+	MinSize        sql.NullInt64
+	MaxSize        sql.NullInt64
+	CreatedAfter   sql.NullTime
+	CreatedBefore  sql.NullTime
+	Name           sql.NullString
+	Tag            sql.NullString
+	Attributes     pqtype.NullRawMessage
+	AlbumID        sql.NullInt64
+	CursorID       sql.NullInt64
+	CursorFileSize sql.NullInt64
+	PageLimit      int32
+	PageOffset     int32
+}
+
+// see ListOrgImagesByFileSizeAsc
+func (q *Queries) ListOrgImagesByFileSizeDesc(ctx context.Context, arg ListOrgImagesByFileSizeDescParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByFileSizeDesc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorFileSize,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrgImagesByNameAsc = `-- name: ListOrgImagesByNameAsc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (file_name , image_id)>($16::text , $15))
+ORDER BY file_name , image_id
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByNameAscParams struct {
+	OrgID         int64
+	ContentType   sql.NullString
+	MinWidth      sql.NullInt32
+	MaxWidth      sql.NullInt32
+	MinHeight     sql.NullInt32
+	MaxHeight     sql.NullInt32
+	MinSize       sql.NullInt64
+	MaxSize       sql.NullInt64
+	CreatedAfter  sql.NullTime
+	CreatedBefore sql.NullTime
+	Name          sql.NullString
+	Tag           sql.NullString
+	Attributes    pqtype.NullRawMessage
+	AlbumID       sql.NullInt64
+	CursorID      sql.NullInt64
+	CursorName    sql.NullString
+	PageLimit     int32
+	PageOffset    int32
+}
+
+// sorted by name with the image id breaking ties , the cursor is the file_name and id of the last image of the previous page
+func (q *Queries) ListOrgImagesByNameAsc(ctx context.Context, arg ListOrgImagesByNameAscParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByNameAsc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorName,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrgImagesByNameDesc = `-- name: ListOrgImagesByNameDesc :many
+SELECT image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token FROM images
+WHERE org_id=$1 AND deleted_at IS NULL
+AND ($2::text IS NULL OR metadata->>'content_type'=$2)
+AND ($3::int IS NULL OR (metadata->>'width')::int>=$3)
+AND ($4::int IS NULL OR (metadata->>'width')::int<=$4)
+AND ($5::int IS NULL OR (metadata->>'height')::int>=$5)
+AND ($6::int IS NULL OR (metadata->>'height')::int<=$6)
+AND ($7::bigint IS NULL OR file_size>=$7)
+AND ($8::bigint IS NULL OR file_size<=$8)
+AND ($9::timestamptz IS NULL OR created_at>=$9)
+AND ($10::timestamptz IS NULL OR created_at<$10)
+AND ($11::text IS NULL OR file_name ILIKE '%' || $11 || '%')
+AND ($12::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=$12))
+AND ($13::jsonb IS NULL OR attributes @> $13)
+AND ($14::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=$14))
+AND ($15::bigint IS NULL OR (file_name , image_id)<($16::text , $15))
+ORDER BY file_name DESC , image_id DESC
+LIMIT $17 OFFSET $18
+`
+
+type ListOrgImagesByNameDescParams struct {
+	OrgID         int64
+	ContentType   sql.NullString
+	MinWidth      sql.NullInt32
+	MaxWidth      sql.NullInt32
+	MinHeight     sql.NullInt32
+	MaxHeight     sql.NullInt32
+	MinSize       sql.NullInt64
+	MaxSize       sql.NullInt64
+	CreatedAfter  sql.NullTime
+	CreatedBefore sql.NullTime
+	Name          sql.NullString
+	Tag           sql.NullString
+	Attributes    pqtype.NullRawMessage
+	AlbumID       sql.NullInt64
+	CursorID      sql.NullInt64
+	CursorName    sql.NullString
+	PageLimit     int32
+	PageOffset    int32
+}
+
+// see ListOrgImagesByNameAsc
+func (q *Queries) ListOrgImagesByNameDesc(ctx context.Context, arg ListOrgImagesByNameDescParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrgImagesByNameDesc,
+		arg.OrgID,
+		arg.ContentType,
+		arg.MinWidth,
+		arg.MaxWidth,
+		arg.MinHeight,
+		arg.MaxHeight,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Name,
+		arg.Tag,
+		arg.Attributes,
+		arg.AlbumID,
+		arg.CursorID,
+		arg.CursorName,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ImageID,
+			&i.UserID,
+			&i.FileName,
+			&i.FileSize,
+			&i.StorageUrl,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ContentHash,
+			&i.Phash,
+			&i.Attributes,
+			&i.Visibility,
+			&i.OrgID,
+			&i.DeletedAt,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.Transformations,
+			&i.UnlistedToken,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreImage = `-- name: RestoreImage :one
+UPDATE images SET deleted_at=NULL , updated_at=now() WHERE image_id=$1 AND deleted_at IS NOT NULL RETURNING image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token
+`
+
+func (q *Queries) RestoreImage(ctx context.Context, imageID int64) (Image, error) {
+	row := q.db.QueryRowContext(ctx, restoreImage, imageID)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const setImagePhash = `-- name: SetImagePhash :exec
+UPDATE images SET phash=$2 WHERE image_id=$1
+`
+
+type SetImagePhashParams struct {
+	ImageID int64
+	Phash   sql.NullInt64
+}
+
+func (q *Queries) SetImagePhash(ctx context.Context, arg SetImagePhashParams) error {
+	_, err := q.db.ExecContext(ctx, setImagePhash, arg.ImageID, arg.Phash)
+	return err
+}
+
+const setImageVisibility = `-- name: SetImageVisibility :one
+UPDATE images SET visibility=$1 ,
+unlisted_token=CASE WHEN $1='unlisted' THEN coalesce(unlisted_token , $2) END , updated_at=now()
+WHERE image_id=$3 RETURNING image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token
+`
+
+type SetImageVisibilityParams struct {
+	Visibility    string
+	UnlistedToken sql.NullString
+	ImageID       int64
+}
+
+// the token of an unlisted image is kept until it stops being unlisted , the next time it is unlisted it gets a new one
+func (q *Queries) SetImageVisibility(ctx context.Context, arg SetImageVisibilityParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, setImageVisibility, arg.Visibility, arg.UnlistedToken, arg.ImageID)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const trashImage = `-- name: TrashImage :one
+UPDATE images SET deleted_at=now() WHERE image_id=$1 AND deleted_at IS NULL RETURNING image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token
+`
+
+func (q *Queries) TrashImage(ctx context.Context, imageID int64) (Image, error) {
+	row := q.db.QueryRowContext(ctx, trashImage, imageID)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
+
+const updateImageAttributes = `-- name: UpdateImageAttributes :one
+UPDATE images SET attributes=(attributes - $1::text[]) || $2::jsonb , updated_at=now() WHERE image_id=$3 RETURNING image_id, user_id, file_name, file_size, storage_url, metadata, created_at, updated_at, content_hash, phash, attributes, visibility, org_id, deleted_at, version, version_created_at, transformations, unlisted_token
+`
+
+type UpdateImageAttributesParams struct {
+	RemoveKeys    []string
+	SetAttributes json.RawMessage
+	ImageID       int64
+}
+
+// the keys in remove_keys are deleted before set_attributes is merged in
+func (q *Queries) UpdateImageAttributes(ctx context.Context, arg UpdateImageAttributesParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, updateImageAttributes, pq.Array(arg.RemoveKeys), arg.SetAttributes, arg.ImageID)
+	var i Image
+	err := row.Scan(
+		&i.ImageID,
+		&i.UserID,
+		&i.FileName,
+		&i.FileSize,
+		&i.StorageUrl,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ContentHash,
+		&i.Phash,
+		&i.Attributes,
+		&i.Visibility,
+		&i.OrgID,
+		&i.DeletedAt,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.Transformations,
+		&i.UnlistedToken,
+	)
+	return i, err
+}
@@ -6,20 +6,184 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/sqlc-dev/pqtype"
 )
 
+type Album struct {
+	AlbumID      int64
+	UserID       int64
+	Title        string
+	Description  string
+	CoverImageID sql.NullInt64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OrgID        int64
+}
+
+type AlbumImage struct {
+	AlbumID  int64
+	ImageID  int64
+	Position int32
+	AddedAt  time.Time
+}
+
+type Blob struct {
+	OrgID       int64
+	ContentHash string
+	FileName    string
+	FileSize    int64
+	StorageUrl  string
+	RefCount    int32
+	CreatedAt   time.Time
+}
+
+type EmailVerification struct {
+	TokenHash string
+	UserID    int64
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
 type Image struct {
-	ImageID    int64
-	UserID     int64
-	FileName   string
-	FileSize   int64
-	StorageUrl string
-	Metadata   pqtype.NullRawMessage
+	ImageID          int64
+	UserID           int64
+	FileName         string
+	FileSize         int64
+	StorageUrl       string
+	Metadata         pqtype.NullRawMessage
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	ContentHash      sql.NullString
+	Phash            sql.NullInt64
+	Attributes       json.RawMessage
+	Visibility       string
+	OrgID            int64
+	DeletedAt        sql.NullTime
+	Version          int32
+	VersionCreatedAt time.Time
+	Transformations  pqtype.NullRawMessage
+	UnlistedToken    sql.NullString
+}
+
+type ImageTag struct {
+	ImageID int64
+	TagID   int64
+}
+
+type ImageVersion struct {
+	VersionID       int64
+	ImageID         int64
+	Version         int32
+	FileName        string
+	FileSize        int64
+	StorageUrl      string
+	Metadata        pqtype.NullRawMessage
+	ContentHash     sql.NullString
+	Phash           sql.NullInt64
+	Transformations pqtype.NullRawMessage
+	CreatedAt       time.Time
+	ArchivedAt      time.Time
+}
+
+type Job struct {
+	JobID         int64
+	UserID        int64
+	ImageID       int64
+	Spec          json.RawMessage
+	Status        string
+	Attempts      int32
+	MaxAttempts   int32
+	LastError     sql.NullString
+	ResultImageID sql.NullInt64
+	RunAt         time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	OrgID         int64
+}
+
+type OrgMember struct {
+	OrgID     int64
+	UserID    int64
+	Role      string
+	CreatedAt time.Time
+}
+
+type Organization struct {
+	OrgID          int64
+	Name           string
+	PersonalUserID sql.NullInt64
+	CreatedAt      time.Time
+}
+
+type Permission struct {
+	PermissionID int64
+	ImageID      sql.NullInt64
+	AlbumID      sql.NullInt64
+	GranteeID    int64
+	GrantedBy    int64
+	Level        string
+	CreatedAt    time.Time
+}
+
+type PresignedCompletion struct {
+	ObjectName string
+	OrgID      int64
 	CreatedAt  time.Time
-	UpdatedAt  time.Time
+}
+
+type Session struct {
+	SessionID        string
+	UserID           int64
+	OrgID            int64
+	RefreshTokenHash string
+	UserAgent        string
+	ClientIp         string
+	IsBlocked        bool
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+	LastUsedAt       time.Time
+}
+
+type ShareLink struct {
+	ShareID      int64
+	Token        string
+	UserID       int64
+	ImageID      sql.NullInt64
+	AlbumID      sql.NullInt64
+	PasswordHash sql.NullString
+	ExpiresAt    sql.NullTime
+	MaxViews     sql.NullInt32
+	ViewCount    int32
+	CreatedAt    time.Time
+	LastViewedAt sql.NullTime
+}
+
+type Tag struct {
+	TagID     int64
+	UserID    int64
+	Name      string
+	CreatedAt time.Time
+	OrgID     int64
+}
+
+type Upload struct {
+	UploadID     string
+	UserID       int64
+	FileName     string
+	UploadLength int64
+	UploadOffset int64
+	Chunks       []string
+	ImageID      sql.NullInt64
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OrgID        int64
+	FinishingAt  sql.NullTime
 }
 
 type User struct {
@@ -32,3 +196,27 @@ type User struct {
 	VerifiedAt        sql.NullTime
 	PasswordChangedAt time.Time
 }
+
+type Webhook struct {
+	WebhookID int64
+	UserID    int64
+	Url       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	DeliveryID     int64
+	WebhookID      int64
+	Event          string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+	NextAttemptAt  time.Time
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+}
@@ -1,13 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
 	_ "github.com/lib/pq"
 )
 
 type Store struct {
 	*Queries
+	db *sql.DB
 }
 
 func NewStore(uri string) (*Store, error) {
@@ -20,5 +23,22 @@ func NewStore(uri string) (*Store, error) {
 	}
 	return &Store{
 		Queries: New(conn),
+		db:      conn,
 	}, err
 }
+
+// ExecTx runs fn with queries bound to a transaction , it is committed when fn returns nil and rolled back otherwise.
+// the error of fn is returned as is so that callers can still inspect it
+func (store *Store) ExecTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start the transaction:%v", err)
+	}
+	if err := fn(store.WithTx(tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w , unable to roll back:%v", err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
@@ -35,6 +35,26 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const getUser = `-- name: GetUser :one
+SELECT user_id, user_name, full_name, password, email, created_at, verified_at, password_changed_at FROM users WHERE user_id=$1
+`
+
+func (q *Queries) GetUser(ctx context.Context, userID int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, userID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.UserName,
+		&i.FullName,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.VerifiedAt,
+		&i.PasswordChangedAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
 SELECT user_id, user_name, full_name, password, email, created_at, verified_at, password_changed_at FROM users WHERE email=$1
 `
@@ -0,0 +1,215 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("the address is not allowed")
+	ErrUnsupportedURL   = errors.New("only http and https urls are supported")
+	ErrTooLarge         = errors.New("the response is too large")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// reservedNetworks are blocked on top of what the net.IP helpers already cover
+var reservedNetworks = mustParseNetworks([]string{
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"64:ff9b::/96",  // NAT64 , can be used to reach private IPv4 addresses
+})
+
+type Options struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	// AllowedNetworks are let through even though they are private , e.g 127.0.0.0/8 for a local test server
+	AllowedNetworks []*net.IPNet
+}
+
+// Fetcher downloads remote files while refusing to connect to private , loopback and link-local addresses
+type Fetcher struct {
+	client  *http.Client
+	options Options
+}
+
+type Result struct {
+	Data        []byte
+	ContentType string
+	FileName    string
+}
+
+func NewFetcher(options Options) *Fetcher {
+	return &Fetcher{client: NewClient(options), options: options}
+}
+
+// NewClient returns an http client that refuses to connect to private , loopback and link-local addresses
+// and follows at most MaxRedirects redirects , it is shared by everything that calls urls supplied by users
+func NewClient(options Options) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		// the check runs on the resolved address so a hostname can't be used to sneak in a private IP
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isAllowed(ip, options.AllowedNetworks) {
+				return fmt.Errorf("%w:%s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		// a proxy would make the dialer check the proxy instead of the target
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > options.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkScheme(req.URL)
+		},
+	}
+}
+
+// Fetch downloads rawURL , the body is rejected once it grows past MaxBytes
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Result, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url:%v", err)
+	}
+	if err := checkScheme(target); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url:%v", err)
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the url:%w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch the url:the server responded with %d", res.StatusCode)
+	}
+	if res.ContentLength > f.options.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, f.options.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the response:%w", err)
+	}
+	if int64(len(data)) > f.options.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	fileName := path.Base(res.Request.URL.Path)
+	if fileName == "/" || fileName == "." {
+		fileName = res.Request.URL.Hostname()
+	}
+	return &Result{
+		Data:        data,
+		ContentType: res.Header.Get("Content-Type"),
+		FileName:    fileName,
+	}, nil
+}
+
+func isAllowed(ip net.IP, allowedNetworks []*net.IPNet) bool {
+	for _, network := range allowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL makes sure rawURL is an absolute http or https url , it is meant for urls that are stored and called later
+func CheckURL(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url:%v", err)
+	}
+	if err := checkScheme(target); err != nil {
+		return err
+	}
+	if target.Hostname() == "" {
+		return errors.New("the url has no host")
+	}
+	return nil
+}
+
+func checkScheme(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return ErrUnsupportedURL
+	}
+	return nil
+}
+
+// ParseNetworks parses a list of CIDRs , a plain IP is treated as a single address network
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address:%s", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network:%v", err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func mustParseNetworks(values []string) []*net.IPNet {
+	networks, err := ParseNetworks(values)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
@@ -0,0 +1,183 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopback lets the tests reach their httptest servers , everything else stays blocked
+var loopback = mustParseNetworks([]string{"127.0.0.0/8"})
+
+func newTestFetcher(allowedNetworks []*net.IPNet) *Fetcher {
+	return NewFetcher(Options{
+		Timeout:         5 * time.Second,
+		MaxBytes:        16,
+		MaxRedirects:    2,
+		AllowedNetworks: allowedNetworks,
+	})
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png bytes"))
+	}))
+	defer server.Close()
+
+	result, err := newTestFetcher(loopback).Fetch(context.Background(), server.URL+"/photos/cat.png")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if string(result.Data) != "png bytes" || result.ContentType != "image/png" || result.FileName != "cat.png" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// without the allowlist the test server is just another service on loopback
+	fetcher := newTestFetcher(nil)
+	for _, target := range []string{
+		server.URL,
+		"http://[::1]/",
+		"http://10.0.0.1/",
+		"http://172.16.0.1/",
+		"http://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[fe80::1]/",
+		"http://100.64.0.1/",
+		"http://0.0.0.0/",
+	} {
+		if _, err := fetcher.Fetch(context.Background(), target); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s: got %v , want %v", target, err, ErrForbiddenAddress)
+		}
+	}
+	if called {
+		t.Error("the server was called")
+	}
+}
+
+func TestFetchRefusesUnsupportedSchemes(t *testing.T) {
+	for _, target := range []string{"file:///etc/passwd", "gopher://example.com/", "ftp://example.com/image.png"} {
+		if _, err := newTestFetcher(nil).Fetch(context.Background(), target); !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("%s: got %v , want %v", target, err, ErrUnsupportedURL)
+		}
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	// /hops/n redirects n more times before answering
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hops/"))
+		if hops > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/hops/%d", hops-1), http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	fetcher := newTestFetcher(loopback)
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/hops/2"); err != nil {
+		t.Errorf("two redirects: %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/hops/3"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("three redirects: got %v , want %v", err, ErrTooManyRedirects)
+	}
+}
+
+func TestFetchRedirectsAreChecked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := newTestFetcher(loopback)
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/metadata"); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("redirect to a link-local address: got %v , want %v", err, ErrForbiddenAddress)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/file"); !errors.Is(err, ErrUnsupportedURL) {
+		t.Errorf("redirect to a file url: got %v , want %v", err, ErrUnsupportedURL)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	body := strings.Repeat("x", 32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// flushing before the end drops the Content-Length so only the read limit can catch it
+			w.Write([]byte(body[:8]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[8:]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	fetcher := newTestFetcher(loopback)
+	for _, target := range []string{server.URL + "/declared", server.URL + "/chunked"} {
+		if _, err := fetcher.Fetch(context.Background(), target); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: got %v , want %v", target, err, ErrTooLarge)
+		}
+	}
+}
+
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		ip      string
+		allowed []*net.IPNet
+		want    bool
+	}{
+		{"93.184.216.34", nil, true},
+		{"2606:2800:220:1::1", nil, true},
+		{"127.0.0.1", nil, false},
+		{"10.1.2.3", nil, false},
+		{"192.168.0.10", nil, false},
+		{"169.254.169.254", nil, false},
+		{"fd00::1", nil, false},
+		{"198.18.0.1", nil, false},
+		{"64:ff9b::a00:1", nil, false},
+		{"127.0.0.1", loopback, true},
+		{"10.1.2.3", loopback, false},
+	}
+	for _, test := range tests {
+		if got := isAllowed(net.ParseIP(test.ip), test.allowed); got != test.want {
+			t.Errorf("isAllowed(%s) = %v , want %v", test.ip, got, test.want)
+		}
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", " 192.168.1.5 ", "", "::1"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(networks) != 3 {
+		t.Fatalf("got %d networks , want 3", len(networks))
+	}
+	if !networks[1].Contains(net.ParseIP("192.168.1.5")) || networks[1].Contains(net.ParseIP("192.168.1.6")) {
+		t.Errorf("a plain address should be a single address network , got %v", networks[1])
+	}
+	if _, err := ParseNetworks([]string{"not-an-ip"}); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
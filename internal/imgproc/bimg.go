@@ -45,6 +45,12 @@ func (b *BimgProccessor) Flip(data []byte) ([]byte, error) {
 	return bimg.NewImage(data).Flip()
 }
 
+func (b *BimgProccessor) StripMetadata(data []byte) ([]byte, error) {
+	options := b.ProcessorOptions
+	options.StripMetadata = true
+	return bimg.NewImage(data).Process(options)
+}
+
 func (b *BimgProccessor) Convert(data []byte, imageType string) ([]byte, error) {
 	switch imageType {
 	case "png":
@@ -0,0 +1,176 @@
+package imgproc
+
+import (
+	"errors"
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+var ErrInvalidBlurHash = errors.New("invalid blurhash")
+
+// BlurHash encodes the image as a BlurHash (https://blurha.sh) , a short string that decodes to a blurred placeholder.
+// xComponents and yComponents (1-9) set how much detail is kept on each axis
+func BlurHash(img *image.NRGBA, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", errors.New("blurhash components must be between 1 and 9")
+	}
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return "", errors.New("the image is empty")
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var r, g, b float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i*x)/float64(width)) * math.Cos(math.Pi*float64(j*y)/float64(height))
+					offset := img.PixOffset(x, y)
+					r += basis * sRGBToLinear(img.Pix[offset])
+					g += basis * sRGBToLinear(img.Pix[offset+1])
+					b += basis * sRGBToLinear(img.Pix[offset+2])
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{r * scale, g * scale, b * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encode83((xComponents-1)+(yComponents-1)*9, 1))
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		var actualMaximum float64
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(value))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encode83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encode83(0, 1))
+	}
+	dc := factors[0]
+	hash.WriteString(encode83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, factor := range factors[1:] {
+		var value int
+		for _, component := range factor {
+			quantised := int(math.Max(0, math.Min(18, math.Floor(signPow(component/maximumValue, 0.5)*9+9.5))))
+			value = value*19 + quantised
+		}
+		hash.WriteString(encode83(value, 2))
+	}
+	return hash.String(), nil
+}
+
+// DecodeBlurHash renders a BlurHash as an image of width x height pixels
+func DecodeBlurHash(hash string, width, height int) (*image.NRGBA, error) {
+	if len(hash) < 6 || width <= 0 || height <= 0 {
+		return nil, ErrInvalidBlurHash
+	}
+	sizeFlag, err := decode83(hash[:1])
+	if err != nil {
+		return nil, err
+	}
+	xComponents, yComponents := sizeFlag%9+1, sizeFlag/9+1
+	if len(hash) != 4+2*xComponents*yComponents {
+		return nil, ErrInvalidBlurHash
+	}
+	quantisedMaximum, err := decode83(hash[1:2])
+	if err != nil {
+		return nil, err
+	}
+	maximumValue := float64(quantisedMaximum+1) / 166
+
+	colors := make([][3]float64, xComponents*yComponents)
+	for i := range colors {
+		if i == 0 {
+			value, err := decode83(hash[2:6])
+			if err != nil {
+				return nil, err
+			}
+			colors[i] = [3]float64{sRGBToLinear(uint8(value >> 16)), sRGBToLinear(uint8(value >> 8)), sRGBToLinear(uint8(value))}
+			continue
+		}
+		value, err := decode83(hash[4+i*2 : 6+i*2])
+		if err != nil {
+			return nil, err
+		}
+		for c, quantised := range [3]int{value / (19 * 19), value / 19 % 19, value % 19} {
+			colors[i][c] = signPow(float64(quantised-9)/9, 2) * maximumValue
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var r, g, b float64
+			for j := 0; j < yComponents; j++ {
+				for i := 0; i < xComponents; i++ {
+					basis := math.Cos(math.Pi*float64(x*i)/float64(width)) * math.Cos(math.Pi*float64(y*j)/float64(height))
+					color := colors[i+j*xComponents]
+					r += color[0] * basis
+					g += color[1] * basis
+					b += color[2] * basis
+				}
+			}
+			offset := img.PixOffset(x, y)
+			img.Pix[offset] = uint8(linearToSRGB(r))
+			img.Pix[offset+1] = uint8(linearToSRGB(g))
+			img.Pix[offset+2] = uint8(linearToSRGB(b))
+			img.Pix[offset+3] = 255
+		}
+	}
+	return img, nil
+}
+
+func encode83(value, length int) string {
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = base83Characters[value%83]
+		value /= 83
+	}
+	return string(encoded)
+}
+
+func decode83(encoded string) (int, error) {
+	var value int
+	for _, character := range encoded {
+		digit := strings.IndexRune(base83Characters, character)
+		if digit < 0 {
+			return 0, ErrInvalidBlurHash
+		}
+		value = value*83 + digit
+	}
+	return value, nil
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
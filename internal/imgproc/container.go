@@ -0,0 +1,318 @@
+package imgproc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"unicode/utf16"
+)
+
+// segments larger than this are skipped instead of being read into memory
+const maxMetadataSegment = 16 << 20
+
+var (
+	exifHeader      = []byte("Exif\x00\x00")
+	xmpHeader       = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccHeader       = []byte("ICC_PROFILE\x00")
+	photoshopHeader = []byte("Photoshop 3.0\x00")
+	jfifHeader      = []byte("JFIF\x00")
+	pngSignature    = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// readContainer fills in what can be read from the structure of the file without decoding the pixels
+func readContainer(file io.ReadSeeker, metadata *ImageMetadata) error {
+	switch metadata.ContentType {
+	case "image/jpeg":
+		return readJPEG(file, metadata)
+	case "image/png":
+		return readPNG(file, metadata)
+	}
+	return nil
+}
+
+// readJPEG walks the marker segments up to the start of the scan
+func readJPEG(file io.ReadSeeker, metadata *ImageMetadata) error {
+	marker := make([]byte, 2)
+	if _, err := io.ReadFull(file, marker); err != nil {
+		return err
+	}
+	if marker[0] != 0xFF || marker[1] != 0xD8 {
+		return errors.New("missing the jpeg start of image marker")
+	}
+	metadata.Pages = 1
+	var icc []byte
+	for {
+		if _, err := io.ReadFull(file, marker[:1]); err != nil {
+			return err
+		}
+		if marker[0] != 0xFF {
+			return errors.New("invalid jpeg marker")
+		}
+		// markers can be preceded by any number of fill bytes
+		for marker[0] == 0xFF {
+			if _, err := io.ReadFull(file, marker[:1]); err != nil {
+				return err
+			}
+		}
+		code := marker[0]
+		// start of scan and end of image , the metadata always comes before them
+		if code == 0xDA || code == 0xD9 {
+			break
+		}
+		// markers without a payload
+		if code == 0x01 || (code >= 0xD0 && code <= 0xD7) {
+			continue
+		}
+		if _, err := io.ReadFull(file, marker); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint16(marker)) - 2
+		if length < 0 {
+			return errors.New("invalid jpeg segment length")
+		}
+		if !isJPEGMetadataMarker(code) {
+			if _, err := file.Seek(length, io.SeekCurrent); err != nil {
+				return err
+			}
+			continue
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(file, payload); err != nil {
+			return err
+		}
+
+		switch {
+		case code == 0xE0 && bytes.HasPrefix(payload, jfifHeader):
+			readJFIF(payload[len(jfifHeader):], metadata)
+		case code == 0xE1 && bytes.HasPrefix(payload, exifHeader):
+			applyEXIF(payload, metadata)
+		case code == 0xE1 && bytes.HasPrefix(payload, xmpHeader):
+			metadata.XMP = parseXMP(payload[len(xmpHeader):])
+		// profiles larger than a segment are split in chunks that carry their sequence number
+		case code == 0xE2 && bytes.HasPrefix(payload, iccHeader) && len(payload) > len(iccHeader)+2:
+			icc = append(icc, payload[len(iccHeader)+2:]...)
+		case code == 0xED && bytes.HasPrefix(payload, photoshopHeader):
+			metadata.IPTC = parsePhotoshopIPTC(payload[len(photoshopHeader):])
+		case isJPEGFrameMarker(code) && len(payload) >= 6:
+			metadata.BitDepth = int(payload[0])
+			switch payload[5] {
+			case 1:
+				metadata.ColorSpace = "gray"
+			case 3:
+				if metadata.ColorSpace == "" {
+					metadata.ColorSpace = "rgb"
+				}
+			case 4:
+				metadata.ColorSpace = "cmyk"
+			}
+		}
+	}
+	if len(icc) > 0 {
+		metadata.ICCProfile = iccDescription(icc)
+	}
+	return nil
+}
+
+func isJPEGMetadataMarker(code byte) bool {
+	return code == 0xE0 || code == 0xE1 || code == 0xE2 || code == 0xED || isJPEGFrameMarker(code)
+}
+
+// isJPEGFrameMarker reports whether code is one of the start of frame markers , C4 , C8 and CC share the range but are not frames
+func isJPEGFrameMarker(code byte) bool {
+	return code >= 0xC0 && code <= 0xCF && code != 0xC4 && code != 0xC8 && code != 0xCC
+}
+
+func readJFIF(payload []byte, metadata *ImageMetadata) {
+	// version (2) , units (1) , x density (2) , y density (2)
+	if len(payload) < 7 || metadata.DPI != 0 {
+		return
+	}
+	density := float64(binary.BigEndian.Uint16(payload[3:5]))
+	switch payload[2] {
+	case 1:
+		metadata.DPI = density
+	case 2:
+		metadata.DPI = roundTo(density*2.54, 2)
+	}
+}
+
+// readPNG walks the chunks of the file , the image data is skipped
+func readPNG(file io.ReadSeeker, metadata *ImageMetadata) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(file, signature); err != nil {
+		return err
+	}
+	if !bytes.Equal(signature, pngSignature) {
+		return errors.New("missing the png signature")
+	}
+	metadata.Pages = 1
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(file, header); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+		if chunkType == "IEND" {
+			return nil
+		}
+		switch chunkType {
+		case "IHDR", "tRNS", "pHYs", "iCCP", "sRGB", "eXIf", "iTXt", "acTL":
+		default:
+			// skip the data and the crc
+			if _, err := file.Seek(length+4, io.SeekCurrent); err != nil {
+				return err
+			}
+			continue
+		}
+		if length > maxMetadataSegment {
+			if _, err := file.Seek(length+4, io.SeekCurrent); err != nil {
+				return err
+			}
+			continue
+		}
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(file, data); err != nil {
+			return err
+		}
+		readPNGChunk(chunkType, data[:length], metadata)
+	}
+}
+
+func readPNGChunk(chunkType string, data []byte, metadata *ImageMetadata) {
+	switch chunkType {
+	case "IHDR":
+		if len(data) < 10 {
+			return
+		}
+		metadata.BitDepth = int(data[8])
+		switch data[9] {
+		case 0:
+			metadata.ColorSpace = "gray"
+		case 4:
+			metadata.ColorSpace = "gray"
+			metadata.HasAlpha = true
+		case 2, 3:
+			metadata.ColorSpace = "rgb"
+		case 6:
+			metadata.ColorSpace = "rgb"
+			metadata.HasAlpha = true
+		}
+	case "tRNS":
+		metadata.HasAlpha = true
+	case "sRGB":
+		metadata.ColorSpace = "srgb"
+	case "pHYs":
+		// pixels per unit on x (4) and y (4) , unit (1) where 1 is the meter
+		if len(data) >= 9 && data[8] == 1 {
+			metadata.DPI = roundTo(float64(binary.BigEndian.Uint32(data[:4]))*0.0254, 2)
+		}
+	case "iCCP":
+		// the chunk starts with the name of the profile
+		if name, _, found := bytes.Cut(data, []byte{0}); found {
+			metadata.ICCProfile = string(name)
+		}
+	case "eXIf":
+		applyEXIF(data, metadata)
+	case "iTXt":
+		if text := readITXt(data, "XML:com.adobe.xmp"); text != nil {
+			metadata.XMP = parseXMP(text)
+		}
+	case "acTL":
+		// animated pngs start with the number of frames
+		if len(data) >= 4 {
+			metadata.Pages = int(binary.BigEndian.Uint32(data[:4]))
+		}
+	}
+}
+
+// readITXt returns the text of an iTXt chunk when its keyword matches
+func readITXt(data []byte, keyword string) []byte {
+	name, rest, found := bytes.Cut(data, []byte{0})
+	if !found || string(name) != keyword || len(rest) < 2 {
+		return nil
+	}
+	compressed := rest[0] == 1
+	// skip the compression flag and method , then the language tag and the translated keyword
+	rest = rest[2:]
+	for i := 0; i < 2; i++ {
+		_, after, found := bytes.Cut(rest, []byte{0})
+		if !found {
+			return nil
+		}
+		rest = after
+	}
+	if !compressed {
+		return rest
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(rest))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	text, err := io.ReadAll(io.LimitReader(reader, maxMetadataSegment))
+	if err != nil {
+		return nil
+	}
+	return text
+}
+
+// iccDescription returns the description tag of an ICC profile which is what tools show as its name
+func iccDescription(profile []byte) string {
+	if len(profile) < 132 {
+		return ""
+	}
+	tagCount := int(binary.BigEndian.Uint32(profile[128:132]))
+	for i := 0; i < tagCount; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(profile) {
+			return ""
+		}
+		if string(profile[entry:entry+4]) != "desc" {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(profile[entry+4 : entry+8]))
+		size := int(binary.BigEndian.Uint32(profile[entry+8 : entry+12]))
+		if offset < 0 || size < 12 || offset+size > len(profile) {
+			return ""
+		}
+		return decodeICCText(profile[offset : offset+size])
+	}
+	return ""
+}
+
+func decodeICCText(tag []byte) string {
+	switch string(tag[:4]) {
+	// ICC v2 , an ascii string prefixed by its length
+	case "desc":
+		length := int(binary.BigEndian.Uint32(tag[8:12]))
+		if 12+length > len(tag) {
+			return ""
+		}
+		return string(bytes.TrimRight(tag[12:12+length], "\x00"))
+	// ICC v4 , a list of utf-16 strings , the first one is used
+	case "mluc":
+		if len(tag) < 28 {
+			return ""
+		}
+		length := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+length > len(tag) {
+			return ""
+		}
+		units := make([]uint16, 0, length/2)
+		for i := offset; i+1 < offset+length; i += 2 {
+			units = append(units, binary.BigEndian.Uint16(tag[i:i+2]))
+		}
+		return string(utf16.Decode(units))
+	}
+	return ""
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
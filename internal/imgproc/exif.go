@@ -0,0 +1,256 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+type CameraInfo struct {
+	Make     string `json:"make,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Lens     string `json:"lens,omitempty"`
+	Software string `json:"software,omitempty"`
+}
+
+type ExposureInfo struct {
+	// ExposureTime is kept as a fraction e.g 1/250 since that is how it is usually displayed
+	ExposureTime string  `json:"exposure_time,omitempty"`
+	FNumber      float64 `json:"f_number,omitempty"`
+	ISO          int     `json:"iso,omitempty"`
+	FocalLength  float64 `json:"focal_length,omitempty"`
+}
+
+type GPSInfo struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+// goexif trusts the counts in the block , a corrupt count makes it allocate gigabytes so blocks are checked against these first
+const (
+	// maxEXIFPayload is the most a jpeg APP1 segment can hold , larger blocks are ignored
+	maxEXIFPayload = 64 << 10
+	// maxEXIFDirs is the most directories that are walked , the main one , the thumbnail and the exif , gps and interop ones
+	maxEXIFDirs = 8
+	// maxEXIFTags is the most entries all the directories can have together
+	maxEXIFTags = 1024
+)
+
+// exifTypeSizes is the size in bytes of a value of each tiff type
+var exifTypeSizes = map[uint16]uint64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// the tags that point to the exif , gps and interop directories
+var exifPointerTags = map[uint16]bool{0x8769: true, 0x8825: true, 0xA005: true}
+
+// applyEXIF copies the fields we care about from an EXIF block , a block that can't be parsed is ignored
+func applyEXIF(payload []byte, metadata *ImageMetadata) {
+	if len(payload) > maxEXIFPayload || checkEXIF(payload) != nil {
+		return
+	}
+	x, err := exif.Decode(bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+
+	camera := &CameraInfo{
+		Make:     exifString(x, exif.Make),
+		Model:    exifString(x, exif.Model),
+		Lens:     exifString(x, exif.LensModel),
+		Software: exifString(x, exif.Software),
+	}
+	if *camera != (CameraInfo{}) {
+		metadata.Camera = camera
+	}
+
+	exposure := &ExposureInfo{
+		FNumber:     roundTo(exifFloat(x, exif.FNumber), 2),
+		ISO:         exifInt(x, exif.ISOSpeedRatings),
+		FocalLength: roundTo(exifFloat(x, exif.FocalLength), 2),
+	}
+	if tag, err := x.Get(exif.ExposureTime); err == nil {
+		if num, den, err := tag.Rat2(0); err == nil && den != 0 {
+			exposure.ExposureTime = formatExposure(num, den)
+		}
+	}
+	if *exposure != (ExposureInfo{}) {
+		metadata.Exposure = exposure
+	}
+
+	if capturedAt, err := x.DateTime(); err == nil {
+		capturedAt = capturedAt.UTC()
+		metadata.CapturedAt = &capturedAt
+	}
+	if latitude, longitude, err := x.LatLong(); err == nil {
+		gps := &GPSInfo{Latitude: roundTo(latitude, 6), Longitude: roundTo(longitude, 6)}
+		if altitude := exifFloat(x, exif.GPSAltitude); altitude != 0 {
+			// a reference of 1 means below sea level
+			if exifInt(x, exif.GPSAltitudeRef) == 1 {
+				altitude = -altitude
+			}
+			altitude = roundTo(altitude, 2)
+			gps.Altitude = &altitude
+		}
+		metadata.GPS = gps
+	}
+
+	metadata.Orientation = exifInt(x, exif.Orientation)
+	if exifInt(x, exif.ColorSpace) == 1 {
+		metadata.ColorSpace = "srgb"
+	}
+	// the container resolution (JFIF , pHYs) wins when there is one
+	if metadata.DPI == 0 {
+		resolution := exifFloat(x, exif.XResolution)
+		switch exifInt(x, exif.ResolutionUnit) {
+		case 2:
+			metadata.DPI = roundTo(resolution, 2)
+		case 3:
+			metadata.DPI = roundTo(resolution*2.54, 2)
+		}
+	}
+}
+
+// checkEXIF walks the directories of the block the way goexif does and rejects it when a directory
+// repeats or an entry claims more data than the block holds
+func checkEXIF(payload []byte) error {
+	data := bytes.TrimPrefix(payload, exifHeader)
+	if len(data) < 8 {
+		return errors.New("the exif block is too short")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return errors.New("invalid exif byte order")
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return errors.New("invalid tiff marker")
+	}
+	visited := map[uint32]bool{}
+	tags := 0
+	// the main directories are chained by their next offset , the others are only reached through the pointer tags
+	pending := []uint32{order.Uint32(data[4:8])}
+	chained := map[uint32]bool{pending[0]: true}
+	for len(pending) > 0 {
+		offset := pending[0]
+		pending = pending[1:]
+		if offset == 0 {
+			continue
+		}
+		if visited[offset] {
+			return errors.New("the exif directories form a loop")
+		}
+		visited[offset] = true
+		if len(visited) > maxEXIFDirs {
+			return errors.New("too many exif directories")
+		}
+		if uint64(offset)+2 > uint64(len(data)) {
+			return errors.New("exif directory out of bounds")
+		}
+		count := int(order.Uint16(data[offset:]))
+		end := uint64(offset) + 2 + uint64(count)*12
+		if end+4 > uint64(len(data)) {
+			return errors.New("exif directory out of bounds")
+		}
+		tags += count
+		if tags > maxEXIFTags {
+			return errors.New("too many exif tags")
+		}
+		for i := 0; i < count; i++ {
+			entry := data[uint64(offset)+2+uint64(i)*12:]
+			tag, kind, values := order.Uint16(entry[0:2]), order.Uint16(entry[2:4]), order.Uint32(entry[4:8])
+			size, ok := exifTypeSizes[kind]
+			if !ok {
+				return fmt.Errorf("unknown exif type %d", kind)
+			}
+			if uint64(values)*size > uint64(len(data)) {
+				return fmt.Errorf("exif tag %#x is larger than the block", tag)
+			}
+			if exifPointerTags[tag] && values == 1 && (kind == 3 || kind == 4) {
+				pointer := uint32(order.Uint16(entry[8:10]))
+				if kind == 4 {
+					pointer = order.Uint32(entry[8:12])
+				}
+				pending = append(pending, pointer)
+			}
+		}
+		if chained[offset] {
+			next := order.Uint32(data[end:])
+			chained[next] = true
+			pending = append(pending, next)
+		}
+	}
+	return nil
+}
+
+func formatExposure(num, den int64) string {
+	if num >= den {
+		return fmt.Sprintf("%g", float64(num)/float64(den))
+	}
+	if num > 1 && den%num == 0 {
+		den, num = den/num, 1
+	}
+	return fmt.Sprintf("%d/%d", num, den)
+}
+
+func exifString(x *exif.Exif, name exif.FieldName) string {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.StringVal {
+		return ""
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(value, "\x00"))
+}
+
+func exifInt(x *exif.Exif, name exif.FieldName) int {
+	tag, err := x.Get(name)
+	if err != nil || tag.Format() != tiff.IntVal {
+		return 0
+	}
+	value, err := tag.Int(0)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func exifFloat(x *exif.Exif, name exif.FieldName) float64 {
+	tag, err := x.Get(name)
+	if err != nil {
+		return 0
+	}
+	switch tag.Format() {
+	case tiff.RatVal:
+		num, den, err := tag.Rat2(0)
+		if err != nil || den == 0 {
+			return 0
+		}
+		return float64(num) / float64(den)
+	case tiff.IntVal:
+		value, err := tag.Int(0)
+		if err != nil {
+			return 0
+		}
+		return float64(value)
+	case tiff.FloatVal:
+		value, err := tag.Float(0)
+		if err != nil {
+			return 0
+		}
+		return value
+	}
+	return 0
+}
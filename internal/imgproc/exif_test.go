@@ -0,0 +1,48 @@
+package imgproc
+
+import (
+	"os"
+	"testing"
+)
+
+// the fixture has an Orientation tag of type LONG with a count of 0xC0000001 , count*4 overflows to 4 so goexif
+// used to read it inline and then allocate ~25GB of values for it
+func TestExtractMetadataCorruptEXIFCount(t *testing.T) {
+	file, err := os.Open("testdata/exif_count_overflow.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	metadata, err := ExtractMetadata(file)
+	if err != nil {
+		t.Fatalf("ExtractMetadata: %v", err)
+	}
+	if metadata.Width != 8 || metadata.Height != 8 {
+		t.Errorf("got %dx%d , want 8x8", metadata.Width, metadata.Height)
+	}
+	if metadata.Orientation != 0 || metadata.Camera != nil {
+		t.Errorf("the corrupt exif block should be ignored , got orientation %d", metadata.Orientation)
+	}
+}
+
+func TestCheckEXIF(t *testing.T) {
+	payload, err := os.ReadFile("testdata/exif_count_overflow.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the APP1 payload starts after the SOI marker , the APP1 marker and its length
+	length := int(payload[4])<<8 | int(payload[5])
+	if err := checkEXIF(payload[6 : 4+length]); err == nil {
+		t.Error("expected the overflowing count to be rejected")
+	}
+
+	valid := []byte("Exif\x00\x00II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00")
+	if err := checkEXIF(valid); err != nil {
+		t.Errorf("valid block rejected: %v", err)
+	}
+
+	loop := []byte("II*\x00\x08\x00\x00\x00\x00\x00\x08\x00\x00\x00")
+	if err := checkEXIF(loop); err == nil {
+		t.Error("expected the directory loop to be rejected")
+	}
+}
@@ -0,0 +1,105 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf8"
+)
+
+type IPTCInfo struct {
+	Title     string   `json:"title,omitempty"`
+	Caption   string   `json:"caption,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	Byline    string   `json:"byline,omitempty"`
+	Copyright string   `json:"copyright,omitempty"`
+	City      string   `json:"city,omitempty"`
+	Country   string   `json:"country,omitempty"`
+}
+
+// the image resource that holds the IPTC-IIM data inside a Photoshop block
+const iptcResourceId = 0x0404
+
+// parsePhotoshopIPTC finds the IPTC resource among the Photoshop image resources of a JPEG APP13 segment
+func parsePhotoshopIPTC(data []byte) *IPTCInfo {
+	for len(data) >= 12 && bytes.HasPrefix(data, []byte("8BIM")) {
+		id := binary.BigEndian.Uint16(data[4:6])
+		// the name is a pascal string padded to an even length
+		nameLength := int(data[6]) + 1
+		if nameLength%2 != 0 {
+			nameLength++
+		}
+		offset := 6 + nameLength
+		if offset+4 > len(data) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		offset += 4
+		if size < 0 || offset+size > len(data) {
+			return nil
+		}
+		if id == iptcResourceId {
+			return parseIIM(data[offset : offset+size])
+		}
+		if size%2 != 0 {
+			size++
+		}
+		if offset+size > len(data) {
+			return nil
+		}
+		data = data[offset+size:]
+	}
+	return nil
+}
+
+// parseIIM reads the application record (2) of an IPTC-IIM block
+func parseIIM(data []byte) *IPTCInfo {
+	info := &IPTCInfo{}
+	// tag marker (1) , record (1) , dataset (1) , size (2)
+	for len(data) >= 5 && data[0] == 0x1C {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:5]))
+		// extended datasets use the top bit of the size , they are never text so they end the walk
+		if size&0x8000 != 0 || 5+size > len(data) {
+			break
+		}
+		value := iimString(data[5 : 5+size])
+		data = data[5+size:]
+		if record != 2 {
+			continue
+		}
+		switch dataset {
+		case 5:
+			info.Title = value
+		case 25:
+			info.Keywords = append(info.Keywords, value)
+		case 80:
+			info.Byline = value
+		case 90:
+			info.City = value
+		case 101:
+			info.Country = value
+		case 116:
+			info.Copyright = value
+		case 120:
+			info.Caption = value
+		}
+	}
+	if info.Title == "" && info.Caption == "" && len(info.Keywords) == 0 && info.Byline == "" &&
+		info.Copyright == "" && info.City == "" && info.Country == "" {
+		return nil
+	}
+	return info
+}
+
+// iimString decodes a dataset value , most files use utf-8 but older ones are latin-1
+func iimString(value []byte) string {
+	if utf8.Valid(value) {
+		return strings.TrimSpace(string(value))
+	}
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return strings.TrimSpace(string(runes))
+}
@@ -0,0 +1,176 @@
+package imgproc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"time"
+
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+type ImageMetadata struct {
+	ContentType string `json:"content_type,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	ColorSpace  string `json:"color_space,omitempty"`
+	ICCProfile  string `json:"icc_profile,omitempty"`
+	BitDepth    int    `json:"bit_depth,omitempty"`
+	HasAlpha    bool   `json:"has_alpha,omitempty"`
+	// Orientation is the EXIF orientation (1-8) , 1 means the pixels are stored upright
+	Orientation int           `json:"orientation,omitempty"`
+	DPI         float64       `json:"dpi,omitempty"`
+	Pages       int           `json:"pages,omitempty"`
+	Camera      *CameraInfo   `json:"camera,omitempty"`
+	Exposure    *ExposureInfo `json:"exposure,omitempty"`
+	CapturedAt  *time.Time    `json:"captured_at,omitempty"`
+	GPS         *GPSInfo      `json:"gps,omitempty"`
+	IPTC        *IPTCInfo     `json:"iptc,omitempty"`
+	XMP         *XMPInfo      `json:"xmp,omitempty"`
+	// DominantColor , Palette and BlurHash are what clients show while the image loads
+	DominantColor string   `json:"dominant_color,omitempty"`
+	Palette       []string `json:"palette,omitempty"`
+	BlurHash      string   `json:"blurhash,omitempty"`
+	// PerceptualHash is kept in its own column , it is nil when the image could not be fully decoded
+	PerceptualHash *uint64 `json:"-"`
+}
+
+// NullPerceptualHash converts the hash to the type of the phash column , the bits are stored as they are
+func (i *ImageMetadata) NullPerceptualHash() sql.NullInt64 {
+	if i.PerceptualHash == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*i.PerceptualHash), Valid: true}
+}
+
+func (i *ImageMetadata) Value() ([]byte, error) {
+	return json.Marshal(i)
+}
+
+func (i *ImageMetadata) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(b, &i)
+}
+
+// ExtractMetadata sniffs the content type and dimensions of the image , reads its EXIF , IPTC , XMP and ICC data and computes its perceptual hash ,
+// colors and BlurHash , the reader is rewound before returning
+func ExtractMetadata(file io.ReadSeeker) (*ImageMetadata, error) {
+	buff := make([]byte, 512)
+	if _, err := file.Read(buff); err != nil {
+		return nil, err
+	}
+	contentType := http.DetectContentType(buff)
+	file.Seek(0, 0)
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Seek(0, 0)
+	metadata := &ImageMetadata{
+		ContentType: contentType,
+		Height:      config.Height,
+		Width:       config.Width,
+	}
+	// the embedded metadata is best effort , a malformed segment only means fewer fields
+	readContainer(file, metadata)
+	file.Seek(0, 0)
+	// the header is cheap to forge , images that claim more pixels than maxDecodePixels keep their metadata but aren't decoded
+	if img, err := decodeLimited(file); err == nil {
+		hash := DHash(img)
+		metadata.PerceptualHash = &hash
+		metadata.setColors(img)
+	}
+	file.Seek(0, 0)
+	return metadata, nil
+}
+
+// setColors computes the palette and the BlurHash from a small sample of the image
+func (i *ImageMetadata) setColors(img image.Image) {
+	small := sample(img, sampleSize)
+	i.Palette = Palette(small, paletteSize)
+	if len(i.Palette) > 0 {
+		i.DominantColor = i.Palette[0]
+	}
+	// keep more detail along the longest side
+	xComponents, yComponents := 4, 3
+	if i.Height > i.Width {
+		xComponents, yComponents = 3, 4
+	}
+	if hash, err := BlurHash(small, xComponents, yComponents); err == nil {
+		i.BlurHash = hash
+	}
+}
+
+// Extension returns the file extension used when an image of contentType is written to an archive
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	default:
+		return ".bin"
+	}
+}
+
+// GPS policies , they decide what API responses show of the location an image was taken at
+const (
+	GPSKeep   = "keep"
+	GPSCoarse = "coarse"
+	GPSRedact = "redact"
+)
+
+// ParseGPSPolicy validates a policy , an empty policy redacts the location
+func ParseGPSPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return GPSRedact, nil
+	case GPSKeep, GPSCoarse, GPSRedact:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown gps policy %q", policy)
+}
+
+// RedactGPS applies the policy to the raw metadata of an image , coarse keeps two decimals (about a kilometre) and drops the altitude.
+// the stored metadata is never changed so that the policy can be relaxed later
+func RedactGPS(raw json.RawMessage, policy string) json.RawMessage {
+	if policy == GPSKeep || len(raw) == 0 {
+		return raw
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return raw
+	}
+	gpsField, ok := fields["gps"]
+	if !ok {
+		return raw
+	}
+	delete(fields, "gps")
+	if policy == GPSCoarse {
+		var gps GPSInfo
+		if err := json.Unmarshal(gpsField, &gps); err == nil {
+			coarse, _ := json.Marshal(GPSInfo{Latitude: roundTo(gps.Latitude, 2), Longitude: roundTo(gps.Longitude, 2)})
+			fields["gps"] = coarse
+		}
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
@@ -0,0 +1,157 @@
+package imgproc
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+)
+
+const (
+	// images are shrunk so that their longest side is at most sampleSize before the colors are analysed
+	sampleSize   = 64
+	paletteSize  = 5
+	opaqueCutoff = 128
+)
+
+// sample box averages the image down to at most size pixels on its longest side
+func sample(img image.Image, size int) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return image.NewNRGBA(image.Rect(0, 0, 0, 0))
+	}
+	sampleWidth, sampleHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			sampleWidth, sampleHeight = size, max(1, height*size/width)
+		} else {
+			sampleWidth, sampleHeight = max(1, width*size/height), size
+		}
+	}
+	type sum struct{ r, g, b, a, n uint64 }
+	sums := make([]sum, sampleWidth*sampleHeight)
+	for y := 0; y < height; y++ {
+		row := (y * sampleHeight / height) * sampleWidth
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			s := &sums[row+x*sampleWidth/width]
+			s.r += uint64(c.R)
+			s.g += uint64(c.G)
+			s.b += uint64(c.B)
+			s.a += uint64(c.A)
+			s.n++
+		}
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, sampleWidth, sampleHeight))
+	for i, s := range sums {
+		if s.n == 0 {
+			continue
+		}
+		out.Pix[i*4] = uint8(s.r / s.n)
+		out.Pix[i*4+1] = uint8(s.g / s.n)
+		out.Pix[i*4+2] = uint8(s.b / s.n)
+		out.Pix[i*4+3] = uint8(s.a / s.n)
+	}
+	return out
+}
+
+type colorBox struct {
+	pixels []color.NRGBA
+}
+
+// widest returns the channel with the largest range and that range
+func (b colorBox) widest() (int, int) {
+	minimum := [3]uint8{255, 255, 255}
+	var maximum [3]uint8
+	for _, p := range b.pixels {
+		for channel, value := range [3]uint8{p.R, p.G, p.B} {
+			minimum[channel] = min(minimum[channel], value)
+			maximum[channel] = max(maximum[channel], value)
+		}
+	}
+	channel, width := 0, -1
+	for c := range minimum {
+		if int(maximum[c])-int(minimum[c]) > width {
+			channel, width = c, int(maximum[c])-int(minimum[c])
+		}
+	}
+	return channel, width
+}
+
+func (b colorBox) average() color.NRGBA {
+	var r, g, bl int
+	for _, p := range b.pixels {
+		r += int(p.R)
+		g += int(p.G)
+		bl += int(p.B)
+	}
+	n := len(b.pixels)
+	return color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: 255}
+}
+
+// Palette returns up to size colors of the image as hex strings using median cut , the most common color comes first.
+// transparent pixels are ignored
+func Palette(img *image.NRGBA, size int) []string {
+	var pixels []color.NRGBA
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		if img.Pix[i+3] < opaqueCutoff {
+			continue
+		}
+		pixels = append(pixels, color.NRGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: 255})
+	}
+	if len(pixels) == 0 {
+		return nil
+	}
+	boxes := []colorBox{{pixels: pixels}}
+	for len(boxes) < size {
+		// split the box with the widest channel range at its median
+		split, splitChannel, splitWidth := -1, 0, 0
+		for i, box := range boxes {
+			if len(box.pixels) < 2 {
+				continue
+			}
+			channel, width := box.widest()
+			if width > splitWidth {
+				split, splitChannel, splitWidth = i, channel, width
+			}
+		}
+		if split < 0 {
+			break
+		}
+		box := boxes[split]
+		sort.Slice(box.pixels, func(i, j int) bool {
+			return channelValue(box.pixels[i], splitChannel) < channelValue(box.pixels[j], splitChannel)
+		})
+		median := len(box.pixels) / 2
+		boxes[split] = colorBox{pixels: box.pixels[:median]}
+		boxes = append(boxes, colorBox{pixels: box.pixels[median:]})
+	}
+	sort.SliceStable(boxes, func(i, j int) bool {
+		return len(boxes[i].pixels) > len(boxes[j].pixels)
+	})
+	palette := make([]string, 0, len(boxes))
+	seen := map[string]bool{}
+	for _, box := range boxes {
+		hex := hexColor(box.average())
+		if !seen[hex] {
+			seen[hex] = true
+			palette = append(palette, hex)
+		}
+	}
+	return palette
+}
+
+func channelValue(c color.NRGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	}
+	return c.B
+}
+
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
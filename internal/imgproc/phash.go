@@ -0,0 +1,99 @@
+package imgproc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+)
+
+// the image is shrunk to hashWidth x hashHeight and each bit compares a pixel with its right neighbour
+const (
+	hashWidth  = 9
+	hashHeight = 8
+)
+
+// maxDecodePixels is the largest image that is decoded in memory , about 160MB as RGBA
+const maxDecodePixels = 40_000_000
+
+var ErrTooManyPixels = errors.New("the image has too many pixels to decode")
+
+// PerceptualHash computes the difference hash (dHash) of an image , similar looking images have hashes with a small Hamming distance
+func PerceptualHash(r io.Reader) (uint64, error) {
+	img, err := decodeLimited(r)
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
+
+// decodeLimited decodes the image after checking the dimensions in its header , a small file can declare dimensions
+// that take gigabytes to decode so anything larger than maxDecodePixels is refused
+func decodeLimited(r io.Reader) (image.Image, error) {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxDecodePixels {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	return img, err
+}
+
+func DHash(img image.Image) uint64 {
+	grid := shrink(img)
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance is the number of bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// shrink averages the luminance of the image over a hashWidth x hashHeight grid
+func shrink(img image.Image) [hashHeight][hashWidth]float64 {
+	var sums [hashHeight][hashWidth]float64
+	var counts [hashHeight][hashWidth]int
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return sums
+	}
+	// jpegs decode to YCbCr , the Y plane is already the luminance
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := 0; y < height; y++ {
+		cellY := y * hashHeight / height
+		for x := 0; x < width; x++ {
+			cellX := x * hashWidth / width
+			var luminance uint8
+			if isYCbCr {
+				luminance = ycbcr.Y[ycbcr.YOffset(bounds.Min.X+x, bounds.Min.Y+y)]
+			} else {
+				luminance = color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			}
+			sums[cellY][cellX] += float64(luminance)
+			counts[cellY][cellX]++
+		}
+	}
+	for y := range sums {
+		for x := range sums[y] {
+			if counts[y][x] > 0 {
+				sums[y][x] /= float64(counts[y][x])
+			}
+		}
+	}
+	return sums
+}
@@ -0,0 +1,54 @@
+package imgproc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+)
+
+// pngBomb is a valid png header for a width x height RGB image followed by a few bytes of pixel data
+func pngBomb(width, height uint32) []byte {
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	chunk := func(kind string, data []byte) {
+		binary.Write(&out, binary.BigEndian, uint32(len(data)))
+		out.WriteString(kind)
+		out.Write(data)
+		binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	ihdr[8], ihdr[9] = 8, 2
+	chunk("IHDR", ihdr)
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	zw.Write(make([]byte, 1024))
+	zw.Close()
+	chunk("IDAT", idat.Bytes())
+	chunk("IEND", nil)
+	return out.Bytes()
+}
+
+func TestExtractMetadataSkipsDecompressionBombs(t *testing.T) {
+	// 100000 x 100000 is 40GB as RGBA
+	metadata, err := ExtractMetadata(bytes.NewReader(pngBomb(100000, 100000)))
+	if err != nil {
+		t.Fatalf("ExtractMetadata: %v", err)
+	}
+	if metadata.Width != 100000 || metadata.Height != 100000 {
+		t.Errorf("got %dx%d", metadata.Width, metadata.Height)
+	}
+	if metadata.PerceptualHash != nil || metadata.BlurHash != "" {
+		t.Error("the image should not have been decoded")
+	}
+}
+
+func TestPerceptualHashRefusesDecompressionBombs(t *testing.T) {
+	if _, err := PerceptualHash(bytes.NewReader(pngBomb(100000, 100000))); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("got %v , want %v", err, ErrTooManyPixels)
+	}
+}
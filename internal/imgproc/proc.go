@@ -7,4 +7,6 @@ type ImageProcessor interface {
 	Zoom(data []byte, factor int) ([]byte, error)
 	Flip(data []byte) ([]byte, error)
 	Convert(data []byte, imageType string) ([]byte, error)
+	// StripMetadata re-encodes the image without its EXIF , XMP and IPTC metadata
+	StripMetadata(data []byte) ([]byte, error)
 }
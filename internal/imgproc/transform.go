@@ -0,0 +1,74 @@
+package imgproc
+
+import (
+	"fmt"
+
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+// Transform applies the requested transformations to the image data in a fixed order
+func Transform(processor ImageProcessor, data []byte, request *models.TransformationsRequest) ([]byte, error) {
+	var err error
+	currentImageData := data
+
+	// Apply transformations in a specific order
+	transformationFuncs := []func() ([]byte, error){
+		func() ([]byte, error) {
+			if request.Resize != nil {
+				return processor.Resize(currentImageData, request.Resize.Width, request.Resize.Height)
+			}
+			return currentImageData, nil
+		},
+		func() ([]byte, error) {
+			if request.Rotate != nil {
+				return processor.Rotate(currentImageData, request.Rotate.Angle)
+			}
+
+			return currentImageData, nil
+		},
+		func() ([]byte, error) {
+			if request.Crop != nil {
+				return processor.Crop(currentImageData, request.Crop.Width, request.Crop.Height)
+			}
+
+			return currentImageData, nil
+		},
+		func() ([]byte, error) {
+			if request.Flip != nil {
+				return processor.Flip(currentImageData)
+			}
+			return currentImageData, nil
+		},
+		func() ([]byte, error) {
+			if request.Convert != nil {
+				return processor.Convert(currentImageData, request.Convert.ImageType)
+			}
+			return currentImageData, nil
+		},
+		func() ([]byte, error) {
+			if request.Zoom != nil {
+				return processor.Zoom(currentImageData, request.Zoom.Factor)
+			}
+			return currentImageData, nil
+		},
+	}
+
+	// Apply transformations sequentially
+	for _, transformFunc := range transformationFuncs {
+
+		// Apply transformation
+		currentImageData, err = transformFunc()
+		if err != nil {
+			return nil, fmt.Errorf("transformation failed: %v", err)
+		}
+	}
+
+	return currentImageData, nil
+}
+
+// Variants are the named transformations that can be requested alongside the originals , e.g when exporting a library
+var Variants = map[string]models.TransformationsRequest{
+	"thumbnail": {Resize: &models.ResizeImageRequest{Width: 256}},
+	"medium":    {Resize: &models.ResizeImageRequest{Width: 1024}},
+	"webp":      {Convert: &models.ConvertImageRequest{ImageType: "webp"}},
+}
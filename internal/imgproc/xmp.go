@@ -0,0 +1,104 @@
+package imgproc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+type XMPInfo struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Creator     string   `json:"creator,omitempty"`
+	Rights      string   `json:"rights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Rating      int      `json:"rating,omitempty"`
+	Label       string   `json:"label,omitempty"`
+	CreatorTool string   `json:"creator_tool,omitempty"`
+}
+
+const (
+	dcNamespace  = "http://purl.org/dc/elements/1.1/"
+	xmpNamespace = "http://ns.adobe.com/xap/1.0/"
+	rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// parseXMP reads the Dublin Core and basic XMP properties of a packet , values can be either attributes of rdf:Description or elements
+func parseXMP(packet []byte) *XMPInfo {
+	info := &XMPInfo{}
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	// the property the text that is being read belongs to
+	var property xml.Name
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space == rdfNamespace && t.Name.Local == "Description" {
+				for _, attr := range t.Attr {
+					setXMPProperty(info, attr.Name, attr.Value)
+				}
+				continue
+			}
+			// rdf:Alt , rdf:Seq , rdf:Bag and rdf:li are containers of the current property
+			if t.Name.Space != rdfNamespace {
+				property = t.Name
+			}
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if t.Name.Space == rdfNamespace && t.Name.Local == "li" {
+				setXMPProperty(info, property, text.String())
+			} else if t.Name == property {
+				setXMPProperty(info, property, text.String())
+				property = xml.Name{}
+			}
+			text.Reset()
+		}
+	}
+	if info.Title == "" && info.Description == "" && info.Creator == "" && info.Rights == "" &&
+		len(info.Keywords) == 0 && info.Rating == 0 && info.Label == "" && info.CreatorTool == "" {
+		return nil
+	}
+	return info
+}
+
+func setXMPProperty(info *XMPInfo, name xml.Name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch name.Space {
+	case dcNamespace:
+		switch name.Local {
+		case "title":
+			info.Title = value
+		case "description":
+			info.Description = value
+		case "creator":
+			if info.Creator == "" {
+				info.Creator = value
+			}
+		case "rights":
+			info.Rights = value
+		case "subject":
+			info.Keywords = append(info.Keywords, value)
+		}
+	case xmpNamespace:
+		switch name.Local {
+		case "Rating":
+			if rating, err := strconv.Atoi(value); err == nil {
+				info.Rating = rating
+			}
+		case "Label":
+			info.Label = value
+		case "CreatorTool":
+			info.CreatorTool = value
+		}
+	}
+}
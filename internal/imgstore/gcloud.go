@@ -2,10 +2,14 @@ package imgstore
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
+	"path"
 	"time"
 
 	"cloud.google.com/go/iam"
@@ -40,42 +44,119 @@ func (g *GCStorage) Upload(ctx context.Context, fileHeader *multipart.FileHeader
 	}
 
 	defer srcFile.Close()
+	return g.UploadStream(ctx, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), srcFile)
+}
+
+// UploadStream copies src into a new object named after fileName , it is used when the data does not come from a multipart form e.g derived images
+func (g *GCStorage) UploadStream(ctx context.Context, fileName, contentType string, src io.Reader) (*UploadResponse, error) {
 	// create a unique filename
-	fileName := fmt.Sprintf("%s_%d", fileHeader.Filename, time.Now().UnixNano())
+	objectName := fmt.Sprintf("%s_%d", fileName, time.Now().UnixNano())
 
 	// get the bucket handle
 	bucket := g.client.Bucket(g.bucketName)
-	objectHandle := bucket.Object(fileName)
+	objectHandle := bucket.Object(objectName)
 
+	// cancelling the writer's context is the only way to abandon the object , closing it would commit whatever was copied
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	writer := objectHandle.NewWriter(ctx)
-	writer.ContentType = fileHeader.Header.Get("Content-Type")
+	writer.ContentType = contentType
 
-	// Copy the file to the Object
-	written, err := io.Copy(writer, srcFile)
+	// Copy the file to the Object , hashing it on the way
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(writer, hash), src)
 	if err != nil {
+		cancel()
+		writer.Close()
+		return nil, fmt.Errorf("unable to copy to storage:%v", err)
+	}
+	// the object is only committed once the writer is closed
+	if err := writer.Close(); err != nil {
 		return nil, fmt.Errorf("unable to copy to storage:%v", err)
 	}
-	defer writer.Close()
 	// make the uploaded images public for Now
 	// if err := objectHandle.ACL().Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
 	// 	return nil, fmt.Errorf("unable to make the file public:%v", err)
 	// }
 	return &UploadResponse{
-		FileName:   fileName,
-		Size:       written,
-		StorageUrl: fmt.Sprintf("https://storage.googleapis.com/%s/%s", g.bucketName, fileName),
+		FileName:    objectName,
+		Size:        written,
+		StorageUrl:  g.storageUrl(objectName),
+		ContentHash: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// SignedUploadURL returns a V4 signed PUT url , the content type , the size range and the generation precondition are part of the signature so the client has to send the same headers ,
+// the precondition only lets the object be created once so its content can't be replaced after the upload was checked
+func (g *GCStorage) SignedUploadURL(ctx context.Context, fileName, contentType string, maxSize int64, expires time.Duration) (*SignedRequest, error) {
+	expiresAt := time.Now().Add(expires)
+	headers := map[string]string{
+		"Content-Type":                contentType,
+		"x-goog-content-length-range": fmt.Sprintf("0,%d", maxSize),
+		"x-goog-if-generation-match":  "0",
+	}
+	url, err := g.client.Bucket(g.bucketName).SignedURL(fileName, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      "PUT",
+		Expires:     expiresAt,
+		ContentType: contentType,
+		Headers: []string{
+			fmt.Sprintf("x-goog-content-length-range:%s", headers["x-goog-content-length-range"]),
+			fmt.Sprintf("x-goog-if-generation-match:%s", headers["x-goog-if-generation-match"]),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign the url:%v", err)
+	}
+	return &SignedRequest{
+		Method:    "PUT",
+		Url:       url,
+		Headers:   headers,
+		ExpiresAt: expiresAt,
 	}, nil
 }
 
-func (g *GCStorage) Download(ctx context.Context, fileName string) (io.Reader, error) {
+// SignedURL returns a V4 signed GET url that stops working after ttl , it lets the bucket stay private
+func (g *GCStorage) SignedURL(ctx context.Context, fileName string, ttl time.Duration) (string, error) {
+	url, err := g.client.Bucket(g.bucketName).SignedURL(fileName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "GET",
+		Expires: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to sign the url:%v", err)
+	}
+	return url, nil
+}
+
+// Stat describes an existing object the same way UploadStream describes the objects it creates
+func (g *GCStorage) Stat(ctx context.Context, fileName string) (*UploadResponse, error) {
+	attrs, err := g.client.Bucket(g.bucketName).Object(fileName).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("unable to get the object attributes:%v", err)
+	}
+	return &UploadResponse{
+		FileName:   attrs.Name,
+		Size:       attrs.Size,
+		StorageUrl: g.storageUrl(attrs.Name),
+	}, nil
+}
+
+func (g *GCStorage) storageUrl(objectName string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", g.bucketName, objectName)
+}
+
+// Download returns a reader for the object , the caller is responsible for closing it
+func (g *GCStorage) Download(ctx context.Context, fileName string) (io.ReadCloser, error) {
 	object := g.client.Bucket(g.bucketName).Object(fileName)
 	reader, err := object.NewReader(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a new reader:%v", err)
 	}
 
-	defer reader.Close()
-
 	return reader, nil
 }
 
@@ -98,9 +179,10 @@ func (g *GCStorage) DownloadTemp(ctx context.Context, fileName string) (string,
 	if err != nil {
 		return "", fmt.Errorf("unable to get the file:%v", err)
 	}
+	defer fileData.Close()
 
-	// Create a temporary file
-	tempFile, err := os.CreateTemp("", fileName)
+	// Create a temporary file , object names under an org prefix contain slashes which CreateTemp rejects in a pattern
+	tempFile, err := os.CreateTemp("", path.Base(fileName)+"_*")
 	if err != nil {
 		return "", fmt.Errorf("unable to save the file locally:%v", err)
 	}
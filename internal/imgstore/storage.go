@@ -2,19 +2,43 @@ package imgstore
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
+	"time"
 )
 
+var ErrObjectNotFound = errors.New("the object does not exist")
+
+// OrgPrefix is the prefix of the objects an organization owns , every object name that is generated for an organization starts with it
+func OrgPrefix(orgId int64) string {
+	return fmt.Sprintf("orgs/%d/", orgId)
+}
+
 type Storage interface {
 	Upload(ctx context.Context, FileHeader *multipart.FileHeader) (*UploadResponse, error)
-	Download(ctx context.Context, fileName string) (io.Reader, error)
+	UploadStream(ctx context.Context, fileName, contentType string, src io.Reader) (*UploadResponse, error)
+	Download(ctx context.Context, fileName string) (io.ReadCloser, error)
 	Delete(ctx context.Context, fileName string) error
 	DownloadTemp(ctx context.Context, fileName string) (string, error)
+	SignedUploadURL(ctx context.Context, fileName, contentType string, maxSize int64, expires time.Duration) (*SignedRequest, error)
+	Stat(ctx context.Context, fileName string) (*UploadResponse, error)
+	SignedURL(ctx context.Context, fileName string, ttl time.Duration) (string, error)
 }
 
 type UploadResponse struct {
 	FileName   string
 	StorageUrl string
 	Size       int64
+	// ContentHash is the hex encoded SHA-256 of the object , it is only set by UploadStream
+	ContentHash string
+}
+
+// SignedRequest is a request the client can make straight to the storage backend , Headers have to be sent as they are
+type SignedRequest struct {
+	Method    string
+	Url       string
+	Headers   map[string]string
+	ExpiresAt time.Time
 }
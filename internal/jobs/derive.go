@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mbeka02/image-service/internal/blobs"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// SaveDerivedImage stores the output of a transformation as a new image created by the user in orgId ,
+// callers pass an organization the user can upload to since the source image can be in one they were only granted access from
+func SaveDerivedImage(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, userId, orgId int64, fileName string, data []byte) (database.Image, error) {
+	metadata, err := imgproc.ExtractMetadata(bytes.NewReader(data))
+	if err != nil {
+		// formats like webp can't be decoded by the std library , keep what we can sniff
+		metadata = &imgproc.ImageMetadata{ContentType: http.DetectContentType(data)}
+	}
+	rawMessage, err := metadata.Value()
+	if err != nil {
+		return database.Image{}, errors.New("failed to get image metadata")
+	}
+
+	uploadResponse, err := fileStorage.UploadStream(ctx, imgstore.OrgPrefix(orgId)+fileName, metadata.ContentType, bytes.NewReader(data))
+	if err != nil {
+		return database.Image{}, err
+	}
+	uploadResponse, err = blobs.Acquire(ctx, store, fileStorage, orgId, uploadResponse)
+	if err != nil {
+		return database.Image{}, err
+	}
+	createdImage, err := store.CreateImage(ctx, database.CreateImageParams{
+		UserID:      userId,
+		OrgID:       orgId,
+		FileName:    uploadResponse.FileName,
+		StorageUrl:  uploadResponse.StorageUrl,
+		FileSize:    uploadResponse.Size,
+		Metadata:    pqtype.NullRawMessage{RawMessage: rawMessage, Valid: true},
+		ContentHash: sql.NullString{String: uploadResponse.ContentHash, Valid: true},
+		Phash:       metadata.NullPerceptualHash(),
+	})
+	if err != nil {
+		blobs.Release(ctx, store, fileStorage, orgId, uploadResponse.ContentHash)
+		return database.Image{}, fmt.Errorf("unable to save the derived image:%v", err)
+	}
+	return createdImage, nil
+}
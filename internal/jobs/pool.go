@@ -0,0 +1,274 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/webhook"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+const (
+	pollInterval = 2 * time.Second
+	// jobs whose heartbeat is older than this are assumed to belong to a crashed worker
+	staleAfter = 10 * time.Minute
+	// how often a worker touches the job it is running , it has to be well below staleAfter
+	heartbeatInterval = time.Minute
+	baseBackoff       = 5 * time.Second
+	maxBackoff        = 10 * time.Minute
+)
+
+// errLeaseLost cancels a job whose heartbeat found that it was released and handed to another worker
+var errLeaseLost = errors.New("the job was released to another worker")
+
+// Pool runs a fixed number of workers that claim transformation jobs from the jobs table
+type Pool struct {
+	Store          *database.Store
+	FileStorage    imgstore.Storage
+	ImageProcessor imgproc.ImageProcessor
+	Webhooks       *webhook.Dispatcher
+	Workers        int
+}
+
+func NewPool(store *database.Store, fileStorage imgstore.Storage, imageProcessor imgproc.ImageProcessor, webhooks *webhook.Dispatcher, workers int) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Pool{
+		Store:          store,
+		FileStorage:    fileStorage,
+		ImageProcessor: imageProcessor,
+		Webhooks:       webhooks,
+		Workers:        workers,
+	}
+}
+
+// Run blocks until ctx is cancelled and every worker has stopped , jobs that are interrupted go back to the queue without using up an attempt
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.releaseStaleJobs(ctx)
+	}()
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		job, err := p.Store.ClaimJob(ctx)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) && ctx.Err() == nil {
+				log.Printf("unable to claim a job:%v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		p.handle(ctx, job)
+	}
+}
+
+func (p *Pool) handle(ctx context.Context, job database.Job) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go p.heartbeat(jobCtx, cancel, job)
+	resultImageId, err := p.process(jobCtx, job)
+	leaseLost := errors.Is(context.Cause(jobCtx), errLeaseLost)
+	cancel(nil)
+	// the job state is persisted even if the pool is shutting down
+	bookkeepingCtx := context.Background()
+	if err != nil && leaseLost {
+		log.Printf("stopped job %d:%v", job.JobID, errLeaseLost)
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		if err := p.Store.ReleaseJob(bookkeepingCtx, database.ReleaseJobParams{
+			JobID:    job.JobID,
+			Attempts: job.Attempts,
+		}); err != nil {
+			log.Printf("unable to release job %d:%v", job.JobID, err)
+		}
+		return
+	}
+	if err == nil {
+		job.Status = StatusCompleted
+		job.ResultImageID = sql.NullInt64{Int64: resultImageId, Valid: true}
+		job.LastError = sql.NullString{}
+		completed, err := p.Store.CompleteJob(bookkeepingCtx, database.CompleteJobParams{
+			JobID:         job.JobID,
+			Attempts:      job.Attempts,
+			ResultImageID: job.ResultImageID,
+		})
+		if err != nil {
+			log.Printf("unable to complete job %d:%v", job.JobID, err)
+			return
+		}
+		if completed == 0 {
+			log.Printf("unable to complete job %d:%v", job.JobID, errLeaseLost)
+			return
+		}
+		p.publish(bookkeepingCtx, job, webhook.EventJobCompleted)
+		return
+	}
+
+	lastError := sql.NullString{String: err.Error(), Valid: true}
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = StatusFailed
+		job.LastError = lastError
+		failed, err := p.Store.FailJob(bookkeepingCtx, database.FailJobParams{
+			JobID:     job.JobID,
+			Attempts:  job.Attempts,
+			LastError: lastError,
+		})
+		if err != nil {
+			log.Printf("unable to fail job %d:%v", job.JobID, err)
+			return
+		}
+		if failed == 0 {
+			log.Printf("unable to fail job %d:%v", job.JobID, errLeaseLost)
+			return
+		}
+		p.publish(bookkeepingCtx, job, webhook.EventJobFailed)
+		return
+	}
+	if _, err := p.Store.RetryJob(bookkeepingCtx, database.RetryJobParams{
+		JobID:     job.JobID,
+		Attempts:  job.Attempts,
+		LastError: lastError,
+		RunAt:     time.Now().Add(Backoff(job.Attempts)),
+	}); err != nil {
+		log.Printf("unable to reschedule job %d:%v", job.JobID, err)
+	}
+}
+
+// heartbeat touches the job every heartbeatInterval while it runs so that it isn't taken for the job of a crashed worker ,
+// the job is cancelled when it turns out to have been released to another worker
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, job database.Job) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			touched, err := p.Store.TouchJob(ctx, database.TouchJobParams{
+				JobID:    job.JobID,
+				Attempts: job.Attempts,
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("unable to touch job %d:%v", job.JobID, err)
+				}
+				continue
+			}
+			if touched == 0 {
+				cancel(errLeaseLost)
+				return
+			}
+		}
+	}
+}
+
+func (p *Pool) publish(ctx context.Context, job database.Job, event string) {
+	if err := p.Webhooks.Publish(ctx, job.UserID, event, job); err != nil {
+		log.Printf("unable to publish %s for job %d:%v", event, job.JobID, err)
+	}
+}
+
+// process applies the job's transformations and saves the output as a new (derived) image , it returns the id of that image
+func (p *Pool) process(ctx context.Context, job database.Job) (int64, error) {
+	request := models.TransformationsRequest{}
+	if err := json.Unmarshal(job.Spec, &request); err != nil {
+		return 0, fmt.Errorf("invalid job spec:%v", err)
+	}
+	image, err := p.Store.GetImage(ctx, job.ImageID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get image:%v", err)
+	}
+	reader, err := p.FileStorage.Download(ctx, image.FileName)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the image:%v", err)
+	}
+
+	output, err := imgproc.Transform(p.ImageProcessor, data, &request)
+	if err != nil {
+		return 0, err
+	}
+	createdImage, err := SaveDerivedImage(ctx, p.Store, p.FileStorage, job.UserID, job.OrgID, fmt.Sprintf("job_%d", job.JobID), output)
+	if err != nil {
+		return 0, err
+	}
+	return createdImage.ImageID, nil
+}
+
+// releaseStaleJobs periodically puts jobs that were left running by a crashed worker back in the queue , or fails them when they used up their attempts ,
+// running jobs have a heartbeat so only abandoned ones go stale
+func (p *Pool) releaseStaleJobs(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := p.Store.ReleaseStaleJobs(ctx, time.Now().Add(-staleAfter))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("unable to release stale jobs:%v", err)
+				}
+				continue
+			}
+			if released > 0 {
+				log.Printf("released %d stale jobs", released)
+			}
+		}
+	}
+}
+
+// Backoff returns how long to wait before retrying a job that has failed attempt times , it doubles on each attempt up to maxBackoff
+func Backoff(attempt int32) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := baseBackoff
+	for i := int32(1); i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -6,23 +6,24 @@ import (
 
 type Mailer struct {
 	Dialer *gomail.Dialer
+	// From is the address emails are sent from
+	From string
 }
 
-func NewMailer(host, password string) *Mailer {
+func NewMailer(host, password, from string) *Mailer {
 	return &Mailer{
 		Dialer: gomail.NewDialer(host, 587, "api", password),
+		From:   from,
 	}
 }
 
-func (m *Mailer) SendEmail() error {
+// SendEmail sends a plain text email to a single recipient
+func (m *Mailer) SendEmail(to, subject, body string) error {
 	msg := gomail.NewMessage()
-	msg.SetHeader("From", "hello@demomailtrap.com")
-	msg.SetHeader("To", "antonymbeka@gmail.com")
-
-	msg.SetHeader("Subject", "Test email")
-
-	// Set email body
-	msg.SetBody("text/plain", "This is the Test Body")
+	msg.SetHeader("From", m.From)
+	msg.SetHeader("To", to)
+	msg.SetHeader("Subject", subject)
+	msg.SetBody("text/plain", body)
 
 	return m.Dialer.DialAndSend(msg)
 }
@@ -0,0 +1,25 @@
+package models
+
+import "github.com/mbeka02/image-service/internal/database"
+
+type CreateAlbumRequest struct {
+	Title       string `json:"title" validate:"required,max=200"`
+	Description string `json:"description" validate:"max=2000"`
+}
+
+// UpdateAlbumRequest only changes the fields that are set , RemoveCover clears the cover image
+type UpdateAlbumRequest struct {
+	Title        *string `json:"title" validate:"omitempty,min=1,max=200"`
+	Description  *string `json:"description" validate:"omitempty,max=2000"`
+	CoverImageID *int64  `json:"cover_image_id"`
+	RemoveCover  bool    `json:"remove_cover"`
+}
+
+type AlbumImagesRequest struct {
+	ImageIDs []int64 `json:"image_ids" validate:"required,min=1,max=500"`
+}
+
+type AlbumResponse struct {
+	Album  database.Album   `json:"album"`
+	Images []database.Image `json:"images"`
+}
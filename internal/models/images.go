@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
 type ResizeImageRequest struct {
 	Width  int `json:"width" validate:"required"`
 	Height int `json:"height" validate:"required"`
@@ -28,3 +34,61 @@ type TransformationsRequest struct {
 	Convert *ConvertImageRequest `json:"convert,omitempty"`
 	Flip    *bool                `json:"flip,omitempty"`
 }
+
+type BatchTransformationsRequest struct {
+	// ImageIDs can be left out to pick the images with the filters of the image list in the query string
+	ImageIDs        []int64                `json:"image_ids" validate:"omitempty,max=100"`
+	Transformations TransformationsRequest `json:"transformations"`
+	// Format is either json (the default) , which saves the outputs as new images , or zip which streams them back
+	Format string `json:"format" validate:"omitempty,oneof=json zip"`
+}
+
+type ImportImageRequest struct {
+	Url string `json:"url" validate:"required,url"`
+}
+
+type PresignUploadRequest struct {
+	FileName    string `json:"file_name" validate:"required"`
+	ContentType string `json:"content_type" validate:"required,oneof=image/jpeg image/png"`
+}
+
+// PresignUploadResponse describes the request the client has to make to upload the file , ObjectName is then sent to the completion endpoint
+type PresignUploadResponse struct {
+	ObjectName string            `json:"object_name"`
+	Method     string            `json:"method"`
+	Url        string            `json:"url"`
+	Headers    map[string]string `json:"headers"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+type CompletePresignedUploadRequest struct {
+	ObjectName string `json:"object_name" validate:"required"`
+}
+
+type TagImageRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=50,dive,required,max=64"`
+}
+
+// BulkTagRequest adds and removes tags on several images at once , images that don't belong to the user are skipped
+type BulkTagRequest struct {
+	ImageIDs []int64  `json:"image_ids" validate:"required,min=1,max=100"`
+	Add      []string `json:"add" validate:"max=50,dive,required,max=64"`
+	Remove   []string `json:"remove" validate:"max=50,dive,required,max=64"`
+}
+
+type BulkTagResponse struct {
+	Added   int64 `json:"added"`
+	Removed int64 `json:"removed"`
+}
+
+// UpdateAttributesRequest deletes the keys in Remove then merges Set into the attributes of the image
+type UpdateAttributesRequest struct {
+	Set    map[string]string `json:"set" validate:"max=50,dive,keys,required,max=64,endkeys,max=1024"`
+	Remove []string          `json:"remove" validate:"max=50,dive,required"`
+}
+
+// ImageVersionsResponse is the image with its current content and the versions it replaced , newest first
+type ImageVersionsResponse struct {
+	Image    database.Image          `json:"image"`
+	Versions []database.ImageVersion `json:"versions"`
+}
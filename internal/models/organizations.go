@@ -0,0 +1,23 @@
+package models
+
+import "github.com/mbeka02/image-service/internal/database"
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=200"`
+}
+
+// AddMemberRequest adds the user with the email to the organization , adding an existing member changes their role
+type AddMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member viewer"`
+}
+
+// SwitchOrganizationResponse has a new access token that is scoped to the organization
+type SwitchOrganizationResponse struct {
+	AccessToken  string                `json:"access_token"`
+	Organization database.Organization `json:"organization"`
+}
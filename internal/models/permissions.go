@@ -0,0 +1,25 @@
+package models
+
+import "github.com/mbeka02/image-service/internal/database"
+
+// GrantPermissionRequest gives the user with the email access to an image or album , granting again changes the level
+type GrantPermissionRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Level string `json:"level" validate:"required,oneof=view transform manage"`
+}
+
+type SharedImage struct {
+	Image database.Image `json:"image"`
+	Level string         `json:"level"`
+}
+
+type SharedAlbum struct {
+	Album database.Album `json:"album"`
+	Level string         `json:"level"`
+}
+
+// SharedWithMeResponse lists what other users have granted the user access to , images shared through an album are only listed under the album
+type SharedWithMeResponse struct {
+	Images []SharedImage `json:"images"`
+	Albums []SharedAlbum `json:"albums"`
+}
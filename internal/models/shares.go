@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+type SetVisibilityRequest struct {
+	Visibility string `json:"visibility" validate:"required,oneof=private unlisted public"`
+}
+
+// CreateShareLinkRequest has only optional limits , a link without any of them works until it is deleted
+type CreateShareLinkRequest struct {
+	// ExpiresIn is the lifetime of the link in seconds
+	ExpiresIn int64  `json:"expires_in" validate:"omitempty,min=60,max=31536000"`
+	Password  string `json:"password" validate:"omitempty,min=6,max=72"`
+	MaxViews  int32  `json:"max_views" validate:"omitempty,min=1"`
+}
+
+type ShareLinkResponse struct {
+	ShareID     int64      `json:"share_id"`
+	Token       string     `json:"token"`
+	Path        string     `json:"path"`
+	ImageID     *int64     `json:"image_id,omitempty"`
+	AlbumID     *int64     `json:"album_id,omitempty"`
+	HasPassword bool       `json:"has_password"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxViews    *int32     `json:"max_views,omitempty"`
+	ViewCount   int32      `json:"view_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func NewShareLinkResponse(link database.ShareLink) ShareLinkResponse {
+	response := ShareLinkResponse{
+		ShareID:     link.ShareID,
+		Token:       link.Token,
+		Path:        "/s/" + link.Token,
+		HasPassword: link.PasswordHash.Valid,
+		ViewCount:   link.ViewCount,
+		CreatedAt:   link.CreatedAt,
+	}
+	if link.ImageID.Valid {
+		response.ImageID = &link.ImageID.Int64
+	}
+	if link.AlbumID.Valid {
+		response.AlbumID = &link.AlbumID.Int64
+	}
+	if link.ExpiresAt.Valid {
+		response.ExpiresAt = &link.ExpiresAt.Time
+	}
+	if link.MaxViews.Valid {
+		response.MaxViews = &link.MaxViews.Int32
+	}
+	return response
+}
+
+// PublicImageResponse is what unauthenticated users see of an image , the owner and storage details are left out
+type PublicImageResponse struct {
+	ImageID   int64           `json:"image_id"`
+	FileName  string          `json:"file_name"`
+	FileSize  int64           `json:"file_size"`
+	Url       string          `json:"url"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type SharedAlbumResponse struct {
+	Title        string                `json:"title"`
+	Description  string                `json:"description"`
+	CoverImageID *int64                `json:"cover_image_id,omitempty"`
+	Images       []PublicImageResponse `json:"images"`
+}
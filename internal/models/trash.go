@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+// TrashedImage is an image in the trash , it is permanently deleted at PurgeAt unless it is restored
+type TrashedImage struct {
+	Image   database.Image `json:"image"`
+	PurgeAt time.Time      `json:"purge_at"`
+}
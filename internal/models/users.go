@@ -1,6 +1,10 @@
 package models
 
-import "github.com/mbeka02/image-service/internal/database"
+import (
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
 
 type CreateUserRequest struct {
 	Fullname string `json:"full_name" validate:"required,min=2"`
@@ -10,6 +14,7 @@ type CreateUserRequest struct {
 type UserResponse struct {
 	Fullname string `json:"full_name" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
+	Verified bool   `json:"verified"`
 }
 
 type LoginRequest struct {
@@ -18,13 +23,57 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        UserResponse `json:"user"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  UserResponse `json:"user"`
+}
+
+// RenewAccessTokenRequest exchanges a refresh token for a new access token and refresh token , OrgID moves the session to another organization
+type RenewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	OrgID        int64  `json:"org_id" validate:"omitempty,min=1"`
+}
+
+type RenewAccessTokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// SessionResponse is a session without its refresh token hash
+type SessionResponse struct {
+	SessionID  string    `json:"session_id"`
+	OrgID      int64     `json:"org_id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIp   string    `json:"client_ip"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func NewSessionResponse(session database.Session) SessionResponse {
+	return SessionResponse{
+		SessionID:  session.SessionID,
+		OrgID:      session.OrgID,
+		UserAgent:  session.UserAgent,
+		ClientIp:   session.ClientIp,
+		ExpiresAt:  session.ExpiresAt,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+	}
 }
 
 func NewUserResponse(user database.User) UserResponse {
 	return UserResponse{
 		Fullname: user.FullName,
 		Email:    user.Email,
+		Verified: user.VerifiedAt.Valid,
 	}
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+type CreateWebhookRequest struct {
+	Url    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=image.uploaded image.transformed image.deleted image.updated job.completed job.failed"`
+}
+
+type WebhookResponse struct {
+	WebhookID int64     `json:"webhook_id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookResponse is the only response that contains the signing secret
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+func NewWebhookResponse(webhook database.Webhook) WebhookResponse {
+	return WebhookResponse{
+		WebhookID: webhook.WebhookID,
+		Url:       webhook.Url,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
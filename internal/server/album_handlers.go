@@ -0,0 +1,336 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+const (
+	defaultAlbumImagesLimit = 50
+	maxAlbumImagesLimit     = 200
+)
+
+func (ih *ImageHandler) handleCreateAlbum(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.CreateAlbumRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	album, err := ih.Store.CreateAlbum(r.Context(), database.CreateAlbumParams{
+		UserID:      tenant.UserID,
+		OrgID:       tenant.OrgID,
+		Title:       request.Title,
+		Description: request.Description,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the album"))
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, APIResponse{
+		Status:  http.StatusCreated,
+		Message: "album created",
+		Data:    album,
+	})
+}
+
+func (ih *ImageHandler) handleGetAlbums(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	albums, err := ih.Store.GetOrgAlbums(r.Context(), tenant.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get albums"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "albums",
+		Data:    albums,
+	})
+}
+
+// handleGetAlbum returns the album with a page of its images in album order
+func (ih *ImageHandler) handleGetAlbum(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessView)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultAlbumImagesLimit
+	}
+	limit = min(limit, maxAlbumImagesLimit)
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	images, err := ih.Store.GetAlbumImages(r.Context(), database.GetAlbumImagesParams{
+		AlbumID: album.AlbumID,
+		Limit:   int32(limit),
+		Offset:  int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the album images"))
+		return
+	}
+	if images == nil {
+		images = []database.Image{}
+	}
+	if err := ih.signImages(r.Context(), images); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "album",
+		Data:    models.AlbumResponse{Album: album, Images: images},
+	})
+}
+
+func (ih *ImageHandler) handleUpdateAlbum(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.UpdateAlbumRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.CoverImageID != nil && request.RemoveCover {
+		respondWithError(w, http.StatusBadRequest, errors.New("cover_image_id and remove_cover can't be used together"))
+		return
+	}
+	params := database.UpdateAlbumParams{AlbumID: album.AlbumID}
+	if request.Title != nil {
+		params.Title = sql.NullString{String: *request.Title, Valid: true}
+	}
+	if request.Description != nil {
+		params.Description = sql.NullString{String: *request.Description, Valid: true}
+	}
+	album, err := ih.Store.UpdateAlbum(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to update the album"))
+		return
+	}
+	if request.CoverImageID != nil || request.RemoveCover {
+		cover := sql.NullInt64{}
+		if request.CoverImageID != nil {
+			cover = sql.NullInt64{Int64: *request.CoverImageID, Valid: true}
+		}
+		album, err = ih.Store.SetAlbumCover(r.Context(), database.SetAlbumCoverParams{
+			CoverImageID: cover,
+			AlbumID:      album.AlbumID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusBadRequest, errors.New("the cover has to be an image of the album"))
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to set the album cover"))
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "album updated",
+		Data:    album,
+	})
+}
+
+// handleDeleteAlbum deletes the album , its images are kept
+func (ih *ImageHandler) handleDeleteAlbum(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	if err := ih.Store.DeleteAlbum(r.Context(), album.AlbumID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the album"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "album deleted",
+	})
+}
+
+// handleAddAlbumImages appends images of the organization to the album , images already in it keep their position
+func (ih *ImageHandler) handleAddAlbumImages(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.AlbumImagesRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	// grants on the album cover the images in it , so only images the user can already manage can be added
+	allowed, err := ih.canManageImages(r.Context(), album.OrgID, request.ImageIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !allowed {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return
+	}
+	added, err := ih.Store.AddImagesToAlbum(r.Context(), database.AddImagesToAlbumParams{
+		AlbumID:  album.AlbumID,
+		ImageIds: request.ImageIDs,
+		OrgID:    album.OrgID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to add the images"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: fmt.Sprintf("%d images added", added),
+	})
+}
+
+// canManageImages reports whether the user has at least manage access to every image of the organization in imageIds ,
+// images that don't exist or are in the trash are left to the insert to skip
+func (ih *ImageHandler) canManageImages(ctx context.Context, orgId int64, imageIds []int64) (bool, error) {
+	payload, err := getAuthPayload(ctx)
+	if err != nil {
+		return false, err
+	}
+	// members can manage every image of their organization , only users who got here through a grant are checked one image at a time
+	level, err := memberAccess(ctx, ih.Store, payload.UserID, orgId, false)
+	if err != nil {
+		return false, errors.New("unable to get the membership")
+	}
+	if level >= accessManage {
+		return true, nil
+	}
+	for _, imageId := range imageIds {
+		image, err := ih.Store.GetImage(ctx, imageId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return false, errors.New("unable to get image")
+		}
+		if image.OrgID != orgId {
+			continue
+		}
+		level, err := imageAccess(ctx, ih.Store, payload.UserID, image)
+		if err != nil {
+			return false, errors.New("unable to check the image permissions")
+		}
+		if level < accessManage {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (ih *ImageHandler) handleRemoveAlbumImages(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.AlbumImagesRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	removed, err := ih.Store.RemoveImagesFromAlbum(r.Context(), database.RemoveImagesFromAlbumParams{
+		AlbumID:  album.AlbumID,
+		ImageIds: request.ImageIDs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to remove the images"))
+		return
+	}
+	// an image that is no longer in the album can't be its cover
+	if album.CoverImageID.Valid {
+		for _, imageId := range request.ImageIDs {
+			if imageId != album.CoverImageID.Int64 {
+				continue
+			}
+			if _, err := ih.Store.SetAlbumCover(r.Context(), database.SetAlbumCoverParams{AlbumID: album.AlbumID}); err != nil {
+				respondWithError(w, http.StatusInternalServerError, errors.New("unable to remove the album cover"))
+				return
+			}
+			break
+		}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: fmt.Sprintf("%d images removed", removed),
+	})
+}
+
+// handleReorderAlbumImages moves the listed images to the front of the album in the given order
+func (ih *ImageHandler) handleReorderAlbumImages(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.AlbumImagesRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := ih.Store.ReorderAlbumImages(r.Context(), database.ReorderAlbumImagesParams{
+		ImageIds: request.ImageIDs,
+		AlbumID:  album.AlbumID,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to reorder the album"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "album reordered",
+	})
+}
+
+// handleDownloadAlbum streams the images of the album as a zip in the same layout as the library export
+func (ih *ImageHandler) handleDownloadAlbum(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessView)
+	if !ok {
+		return
+	}
+	variants, err := getExportVariants(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="album-%d.zip"`, album.AlbumID))
+	zipWriter := zip.NewWriter(w)
+	nextPage := func(ctx context.Context, offset int32) ([]database.Image, error) {
+		return ih.Store.GetAlbumImages(ctx, database.GetAlbumImagesParams{
+			AlbumID: album.AlbumID,
+			Limit:   exportPageSize,
+			Offset:  offset,
+		})
+	}
+	if err := ih.writeExport(r.Context(), zipWriter, nextPage, variants); err != nil {
+		log.Printf("download of album %d aborted:%v", album.AlbumID, err)
+		return
+	}
+	if err := zipWriter.Close(); err != nil {
+		log.Printf("unable to finish the album archive:%v", err)
+	}
+}
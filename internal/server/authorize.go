@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+// the access a user has to an image or album , the values match the ranks GetImageAccessLevel and GetAlbumAccessLevel return
+type accessLevel int32
+
+const (
+	accessNone accessLevel = iota
+	accessView
+	accessTransform
+	accessManage
+	// only owners can change the visibility , create share links or grant permissions
+	accessOwner
+)
+
+// the roles of organization members , every role can do what the roles below it can
+const (
+	roleOwner  = "owner"
+	roleAdmin  = "admin"
+	roleMember = "member"
+	roleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	roleViewer: 1,
+	roleMember: 2,
+	roleAdmin:  3,
+	roleOwner:  4,
+}
+
+// roleAccess is the access a member has to the images and albums of their organization , members fully control what they created
+func roleAccess(role string, creator bool) accessLevel {
+	switch role {
+	case roleOwner, roleAdmin:
+		return accessOwner
+	case roleMember:
+		if creator {
+			return accessOwner
+		}
+		return accessManage
+	case roleViewer:
+		return accessView
+	}
+	return accessNone
+}
+
+// memberAccess returns the access the user has through their membership of the organization , users outside it have none
+func memberAccess(ctx context.Context, store *database.Store, userId, orgId int64, creator bool) (accessLevel, error) {
+	member, err := store.GetOrgMember(ctx, database.GetOrgMemberParams{OrgID: orgId, UserID: userId})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return accessNone, nil
+		}
+		return accessNone, err
+	}
+	return roleAccess(member.Role, creator), nil
+}
+
+// imageAccess returns the level of access the user has to the image , it is the highest of the access from the organization and the granted permissions.
+// grants on an album cover the images in it
+func imageAccess(ctx context.Context, store *database.Store, userId int64, image database.Image) (accessLevel, error) {
+	level, err := memberAccess(ctx, store, userId, image.OrgID, image.UserID == userId)
+	if err != nil || level == accessOwner {
+		return level, err
+	}
+	granted, err := store.GetImageAccessLevel(ctx, database.GetImageAccessLevelParams{
+		GranteeID: userId,
+		ImageID:   sql.NullInt64{Int64: image.ImageID, Valid: true},
+	})
+	if err != nil {
+		return accessNone, err
+	}
+	return max(level, accessLevel(granted)), nil
+}
+
+func albumAccess(ctx context.Context, store *database.Store, userId int64, album database.Album) (accessLevel, error) {
+	level, err := memberAccess(ctx, store, userId, album.OrgID, album.UserID == userId)
+	if err != nil || level == accessOwner {
+		return level, err
+	}
+	granted, err := store.GetAlbumAccessLevel(ctx, database.GetAlbumAccessLevelParams{
+		GranteeID: userId,
+		AlbumID:   sql.NullInt64{Int64: album.AlbumID, Valid: true},
+	})
+	if err != nil {
+		return accessNone, err
+	}
+	return max(level, accessLevel(granted)), nil
+}
+
+// getAuthorizedImage loads the image in the url , it writes the error response and returns false when the user doesn't have at least the required access
+func (ih *ImageHandler) getAuthorizedImage(w http.ResponseWriter, r *http.Request, required accessLevel) (database.Image, bool) {
+	return ih.authorizeImage(w, r, required, ih.Store.GetImage)
+}
+
+// getTrashedImage is getAuthorizedImage for images in the trash
+func (ih *ImageHandler) getTrashedImage(w http.ResponseWriter, r *http.Request, required accessLevel) (database.Image, bool) {
+	return ih.authorizeImage(w, r, required, ih.Store.GetTrashedImage)
+}
+
+func (ih *ImageHandler) authorizeImage(w http.ResponseWriter, r *http.Request, required accessLevel, getImage func(context.Context, int64) (database.Image, error)) (database.Image, bool) {
+	imageId, err := getImageId(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Image{}, false
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Image{}, false
+	}
+	image, err := getImage(r.Context(), int64(imageId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("image not found"))
+			return database.Image{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+		return database.Image{}, false
+	}
+	level, err := imageAccess(r.Context(), ih.Store, payload.UserID, image)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to check the image permissions"))
+		return database.Image{}, false
+	}
+	if level < required {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return database.Image{}, false
+	}
+	return image, true
+}
+
+// getAuthorizedAlbum loads the album in the url , it writes the error response and returns false when the user doesn't have at least the required access
+func (ih *ImageHandler) getAuthorizedAlbum(w http.ResponseWriter, r *http.Request, required accessLevel) (database.Album, bool) {
+	albumId, err := strconv.ParseInt(chi.URLParam(r, "albumId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Album{}, false
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Album{}, false
+	}
+	album, err := ih.Store.GetAlbum(r.Context(), albumId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("album not found"))
+			return database.Album{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get album"))
+		return database.Album{}, false
+	}
+	level, err := albumAccess(r.Context(), ih.Store, payload.UserID, album)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to check the album permissions"))
+		return database.Album{}, false
+	}
+	if level < required {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return database.Album{}, false
+	}
+	return album, true
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/jobs"
+	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/webhook"
+)
+
+const (
+	// maxBatchConcurrency bounds how many images of a batch are processed at the same time
+	maxBatchConcurrency = 4
+	// maxBatchImages is the most images a batch covers , filtered batches are paged with the cursor of the image list
+	maxBatchImages = 100
+)
+
+// BatchResult is the outcome of the batch for a single image
+type BatchResult struct {
+	ImageID int64           `json:"image_id"`
+	Error   string          `json:"error,omitempty"`
+	Image   *database.Image `json:"image,omitempty"`
+	// data holds the transformed image when the batch is returned as a zip
+	data []byte
+}
+
+func (ih *ImageHandler) handleBatchTransformations(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.BatchTransformationsRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	filtered := hasImageFilters(r)
+	if len(request.ImageIDs) > 0 && filtered {
+		respondWithError(w, http.StatusBadRequest, errors.New("pick the images with either image_ids or the filters in the query string"))
+		return
+	}
+	if len(request.ImageIDs) == 0 {
+		if !filtered {
+			respondWithError(w, http.StatusBadRequest, errors.New("image_ids or a filter in the query string is required"))
+			return
+		}
+		imageIds, status, err := ih.filterBatchImages(w, r)
+		if err != nil {
+			respondWithError(w, status, err)
+			return
+		}
+		request.ImageIDs = imageIds
+	}
+	if request.Format != "zip" {
+		results := ih.runBatch(r.Context(), payload.UserID, tenant.OrgID, request, true, nil)
+		respondWithJSON(w, http.StatusOK, APIResponse{
+			Status:  http.StatusOK,
+			Message: "batch processed",
+			Data:    results,
+		})
+		return
+	}
+
+	// results are written to the archive as soon as they are ready
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="batch.zip"`)
+	zipWriter := zip.NewWriter(w)
+	var mu sync.Mutex
+	results := ih.runBatch(r.Context(), payload.UserID, tenant.OrgID, request, false, func(result *BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := writeZipEntry(zipWriter, fmt.Sprintf("%d%s", result.ImageID, imgproc.Extension(http.DetectContentType(result.data))), result.data); err != nil {
+			result.Error = err.Error()
+		}
+		result.data = nil
+	})
+	manifest, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		err = writeZipEntry(zipWriter, "results.json", manifest)
+	}
+	if err != nil {
+		log.Printf("unable to write the batch manifest:%v", err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		log.Printf("unable to finish the batch archive:%v", err)
+	}
+}
+
+// filterBatchImages picks the images of the batch with the filters , sort and cursor of GET /images ,
+// a batch covers a page of up to maxBatchImages images and the next page is linked in the Link header like in the list
+func (ih *ImageHandler) filterBatchImages(w http.ResponseWriter, r *http.Request) ([]int64, int, error) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	params, err := getImageFilters(r, tenant.OrgID)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if r.URL.Query().Get("limit") == "" {
+		params.PageLimit = maxBatchImages
+	}
+	// one extra row tells us whether there is a next page
+	limit := params.PageLimit
+	params.PageLimit++
+	images, err := listOrgImages(r.Context(), ih.Store, params)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("unable to get images")
+	}
+	if len(images) > int(limit) {
+		images = images[:limit]
+		w.Header().Set("Link", nextPageLink(r, encodeImageCursor(params, images[len(images)-1])))
+	}
+	imageIds := make([]int64, 0, len(images))
+	for _, image := range images {
+		imageIds = append(imageIds, image.ImageID)
+	}
+	return imageIds, http.StatusOK, nil
+}
+
+// runBatch transforms every image of the request with at most maxBatchConcurrency workers , onResult is called for each successful image.
+// saved images go to orgId , the active organization of the user
+func (ih *ImageHandler) runBatch(ctx context.Context, userId, orgId int64, request models.BatchTransformationsRequest, save bool, onResult func(*BatchResult)) []*BatchResult {
+	results := make([]*BatchResult, len(request.ImageIDs))
+	semaphore := make(chan struct{}, maxBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, imageId := range request.ImageIDs {
+		results[i] = &BatchResult{ImageID: imageId}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(result *BatchResult) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			if err := ih.transformForBatch(ctx, userId, orgId, &request.Transformations, save, result); err != nil {
+				result.Error = err.Error()
+				return
+			}
+			if onResult != nil {
+				onResult(result)
+			}
+		}(results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func (ih *ImageHandler) transformForBatch(ctx context.Context, userId, orgId int64, request *models.TransformationsRequest, save bool, result *BatchResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	image, err := ih.Store.GetImage(ctx, result.ImageID)
+	if err != nil {
+		return errors.New("unable to get image")
+	}
+	level, err := imageAccess(ctx, ih.Store, userId, image)
+	if err != nil {
+		return errors.New("unable to check the image permissions")
+	}
+	if level < accessTransform {
+		return errors.New("unauthorized!")
+	}
+	reader, err := ih.FileStorage.Download(ctx, image.FileName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("unable to read the image:%v", err)
+	}
+	output, err := imgproc.Transform(ih.ImageProcessor, data, request)
+	if err != nil {
+		return err
+	}
+	if save {
+		derivedImage, err := jobs.SaveDerivedImage(ctx, ih.Store, ih.FileStorage, userId, orgId, fmt.Sprintf("batch_%d", image.ImageID), output)
+		if err != nil {
+			return err
+		}
+		if err := ih.signImage(ctx, &derivedImage); err != nil {
+			return err
+		}
+		result.Image = &derivedImage
+	} else {
+		result.data = output
+	}
+	ih.publish(ctx, image.UserID, webhook.EventImageTransformed, map[string]interface{}{
+		"image_id":        image.ImageID,
+		"transformations": request,
+	})
+	return nil
+}
+
+func writeZipEntry(zipWriter *zip.Writer, name string, data []byte) error {
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zipMethod(http.DetectContentType(data))})
+	if err != nil {
+		return fmt.Errorf("unable to add %s to the archive:%v", name, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("unable to add %s to the archive:%v", name, err)
+	}
+	return nil
+}
+
+// zipMethod stores the formats that are already compressed since deflating them again only costs cpu
+func zipMethod(contentType string) uint16 {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp", "image/gif":
+		return zip.Store
+	}
+	return zip.Deflate
+}
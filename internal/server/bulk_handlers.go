@@ -0,0 +1,215 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+const (
+	maxBulkFiles = 50
+	// maxBulkSize caps both the request body and the uncompressed size of everything in it
+	maxBulkSize     = 200 << 20
+	maxBulkFileSize = 25 << 20
+	// entries that inflate more than this are treated as zip bombs
+	maxZipRatio = 100
+	// memory used to buffer the multipart form , the rest spills to disk
+	bulkFormMemory = 32 << 20
+)
+
+var errBulkLimit = errors.New("bulk upload limit exceeded")
+
+// BulkUploadResult reports what happened to a single file of a bulk upload
+type BulkUploadResult struct {
+	FileName string          `json:"file_name"`
+	Error    string          `json:"error,omitempty"`
+	Image    *database.Image `json:"image,omitempty"`
+}
+
+// bulkUpload keeps track of the limits while the files of a request are processed
+type bulkUpload struct {
+	ih         *ImageHandler
+	userId     int64
+	orgId      int64
+	duplicates string
+	files      int
+	size       int64
+	results    []BulkUploadResult
+}
+
+// handleBulkUpload accepts any number of file fields , zip archives are unpacked and every entry is treated as an upload
+func (ih *ImageHandler) handleBulkUpload(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	duplicates, err := getDuplicatePolicy(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkSize)
+	if err := r.ParseMultipartForm(bulkFormMemory); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("bad request:%v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	upload := &bulkUpload{ih: ih, userId: tenant.UserID, orgId: tenant.OrgID, duplicates: duplicates}
+	// map order is random , the fields are sorted so that the results and the point the limits are hit at don't change between requests ,
+	// the files of a field , e.g images , keep the order they were sent in
+	fields := make([]string, 0, len(r.MultipartForm.File))
+	for field := range r.MultipartForm.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		for _, fileHeader := range r.MultipartForm.File[field] {
+			if err := upload.addFormFile(r.Context(), fileHeader); err != nil {
+				// the files that were stored before the limit was hit are still reported
+				respondWithJSON(w, http.StatusRequestEntityTooLarge, APIResponse{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: err.Error(),
+					Data:    upload.results,
+				})
+				return
+			}
+		}
+	}
+	if len(upload.results) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("bad request:no files were uploaded"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "bulk upload processed",
+		Data:    upload.results,
+	})
+}
+
+// addFormFile stores a single form file or every entry of a zip archive , it only returns an error when a limit is exceeded
+func (b *bulkUpload) addFormFile(ctx context.Context, fileHeader *multipart.FileHeader) error {
+	fileName := fileHeader.Filename
+	file, err := fileHeader.Open()
+	if err != nil {
+		b.fail(fileName, fmt.Errorf("unable to open the file:%v", err))
+		return nil
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(file, sniff)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		b.fail(fileName, fmt.Errorf("unable to read the file:%v", err))
+		return nil
+	}
+	if http.DetectContentType(sniff[:n]) == "application/zip" {
+		return b.addArchive(ctx, fileName, file, fileHeader.Size)
+	}
+
+	if fileHeader.Size > maxBulkFileSize {
+		b.fail(fileName, fmt.Errorf("the file is larger than %d bytes", maxBulkFileSize))
+		return nil
+	}
+	if err := b.reserve(fileHeader.Size); err != nil {
+		return err
+	}
+	b.add(ctx, fileName, file)
+	return nil
+}
+
+func (b *bulkUpload) addArchive(ctx context.Context, archiveName string, file io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		b.fail(archiveName, fmt.Errorf("invalid zip archive:%v", err))
+		return nil
+	}
+	for _, entry := range archive.File {
+		name := path.Base(entry.Name)
+		// skip folders and the metadata some archivers add
+		if entry.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+		entryName := archiveName + "/" + entry.Name
+		if entry.CompressedSize64 > 0 && entry.UncompressedSize64/entry.CompressedSize64 > maxZipRatio {
+			return fmt.Errorf("%w:%s has a suspicious compression ratio", errBulkLimit, entryName)
+		}
+		if entry.UncompressedSize64 > maxBulkFileSize {
+			b.fail(entryName, fmt.Errorf("the file is larger than %d bytes", maxBulkFileSize))
+			continue
+		}
+		if err := b.reserve(int64(entry.UncompressedSize64)); err != nil {
+			return err
+		}
+		data, err := readZipEntry(entry)
+		if err != nil {
+			b.fail(entryName, err)
+			continue
+		}
+		b.add(ctx, name, bytes.NewReader(data))
+	}
+	return nil
+}
+
+// reserve counts a file against the request limits before it is processed
+func (b *bulkUpload) reserve(size int64) error {
+	b.files++
+	b.size += size
+	if b.files > maxBulkFiles {
+		return fmt.Errorf("%w:at most %d files can be uploaded at once", errBulkLimit, maxBulkFiles)
+	}
+	if b.size > maxBulkSize {
+		return fmt.Errorf("%w:the files are larger than %d bytes in total", errBulkLimit, maxBulkSize)
+	}
+	return nil
+}
+
+func (b *bulkUpload) add(ctx context.Context, fileName string, file io.ReadSeeker) {
+	metadata, err := validateImage(file)
+	if err != nil {
+		b.fail(fileName, err)
+		return
+	}
+	createdImage, err := b.ih.storeImage(ctx, b.userId, b.orgId, fileName, metadata, file, b.duplicates)
+	if err != nil {
+		b.fail(fileName, err)
+		return
+	}
+	if err := b.ih.signImage(ctx, &createdImage); err != nil {
+		b.fail(fileName, err)
+		return
+	}
+	b.results = append(b.results, BulkUploadResult{FileName: fileName, Image: &createdImage})
+}
+
+func (b *bulkUpload) fail(fileName string, err error) {
+	b.results = append(b.results, BulkUploadResult{FileName: fileName, Error: err.Error()})
+}
+
+// readZipEntry inflates an entry without trusting the sizes in its header
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the file:%v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, int64(entry.UncompressedSize64)+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the file:%v", err)
+	}
+	if uint64(len(data)) > entry.UncompressedSize64 {
+		return nil, errors.New("the file is larger than its archive header claims")
+	}
+	return data, nil
+}
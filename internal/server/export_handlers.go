@@ -0,0 +1,171 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+)
+
+const exportPageSize = 100
+
+// exportEntry is a row of manifest.json
+type exportEntry struct {
+	ImageID    int64           `json:"image_id"`
+	FileName   string          `json:"file_name"`
+	FileSize   int64           `json:"file_size"`
+	StorageUrl string          `json:"storage_url"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Files      []string        `json:"files"`
+	Errors     []string        `json:"errors,omitempty"`
+}
+
+// handleExportImages streams every image of the organization as a zip , the optional variants query param is a comma separated list of imgproc.Variants
+func (ih *ImageHandler) handleExportImages(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	variants, err := getExportVariants(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="images.zip"`)
+	zipWriter := zip.NewWriter(w)
+	nextPage := func(ctx context.Context, offset int32) ([]database.Image, error) {
+		return ih.Store.GetOrgImages(ctx, database.GetOrgImagesParams{
+			OrgID:  tenant.OrgID,
+			Limit:  exportPageSize,
+			Offset: offset,
+		})
+	}
+	if err := ih.writeExport(r.Context(), zipWriter, nextPage, variants); err != nil {
+		// the headers are already sent , all we can do is stop writing
+		log.Printf("export for organization %d aborted:%v", tenant.OrgID, err)
+		return
+	}
+	if err := zipWriter.Close(); err != nil {
+		log.Printf("unable to finish the export archive:%v", err)
+	}
+}
+
+// getExportVariants reads the variants query param , a comma separated list of imgproc.Variants
+func getExportVariants(r *http.Request) ([]string, error) {
+	var variants []string
+	if param := r.URL.Query().Get("variants"); param != "" {
+		for _, variant := range strings.Split(param, ",") {
+			if _, ok := imgproc.Variants[variant]; !ok {
+				return nil, fmt.Errorf("unknown variant:%s", variant)
+			}
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+// writeExport pages through the images returned by nextPage , which gets pages of exportPageSize , and writes them to the archive followed by the manifest
+func (ih *ImageHandler) writeExport(ctx context.Context, zipWriter *zip.Writer, nextPage func(ctx context.Context, offset int32) ([]database.Image, error), variants []string) error {
+	manifest := []exportEntry{}
+	for offset := int32(0); ; offset += exportPageSize {
+		images, err := nextPage(ctx, offset)
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			manifest = append(manifest, ih.exportImage(ctx, zipWriter, image, variants))
+		}
+		if len(images) < exportPageSize {
+			break
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entry, err := zipWriter.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+// exportImage copies the original straight from storage into the archive and then renders the variants , failures are recorded in the manifest
+func (ih *ImageHandler) exportImage(ctx context.Context, zipWriter *zip.Writer, image database.Image, variants []string) exportEntry {
+	entry := exportEntry{
+		ImageID:    image.ImageID,
+		FileName:   image.FileName,
+		FileSize:   image.FileSize,
+		StorageUrl: image.StorageUrl,
+		CreatedAt:  image.CreatedAt,
+		UpdatedAt:  image.UpdatedAt,
+		Files:      []string{},
+	}
+	metadata := imgproc.ImageMetadata{}
+	if image.Metadata.Valid {
+		entry.Metadata = imgproc.RedactGPS(image.Metadata.RawMessage, ih.GPSPolicy)
+		json.Unmarshal(image.Metadata.RawMessage, &metadata)
+	}
+
+	reader, err := ih.FileStorage.Download(ctx, image.FileName)
+	if err != nil {
+		entry.Errors = append(entry.Errors, err.Error())
+		return entry
+	}
+	defer reader.Close()
+	name := fmt.Sprintf("originals/%d%s", image.ImageID, imgproc.Extension(metadata.ContentType))
+	writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zipMethod(metadata.ContentType)})
+	if err != nil {
+		entry.Errors = append(entry.Errors, err.Error())
+		return entry
+	}
+	if len(variants) == 0 {
+		if _, err := io.Copy(writer, reader); err != nil {
+			entry.Errors = append(entry.Errors, err.Error())
+			return entry
+		}
+		entry.Files = append(entry.Files, name)
+		return entry
+	}
+
+	// the variants need the whole image in memory so it is kept while it is copied
+	var data bytes.Buffer
+	if _, err := io.Copy(io.MultiWriter(writer, &data), reader); err != nil {
+		entry.Errors = append(entry.Errors, err.Error())
+		return entry
+	}
+	entry.Files = append(entry.Files, name)
+	for _, variant := range variants {
+		request := imgproc.Variants[variant]
+		output, err := imgproc.Transform(ih.ImageProcessor, data.Bytes(), &request)
+		if err != nil {
+			entry.Errors = append(entry.Errors, fmt.Sprintf("%s:%v", variant, err))
+			continue
+		}
+		variantName := fmt.Sprintf("variants/%s/%d%s", variant, image.ImageID, imgproc.Extension(http.DetectContentType(output)))
+		if err := writeZipEntry(zipWriter, variantName, output); err != nil {
+			entry.Errors = append(entry.Errors, err.Error())
+			continue
+		}
+		entry.Files = append(entry.Files, variantName)
+	}
+	return entry
+}
@@ -23,6 +23,14 @@ type APIResponse struct {
 	Status  int         `json:"status"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	// Pagination is only set on list responses that support cursors
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes how to get the next page , NextCursor is empty on the last page
+type Pagination struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
 }
 
 // ValidationError represents validation error details
@@ -45,7 +53,7 @@ func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) err
 }
 
 func respondWithImage(w http.ResponseWriter, data []byte) error {
-	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Type", http.DetectContentType(data))
 	if _, err := w.Write(data); err != nil {
 		return fmt.Errorf("unable to write the data to the connection:%v", err)
 	}
@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/sqlc-dev/pqtype"
+)
+
+const (
+	defaultImagesLimit = 10
+	maxImagesLimit     = 100
+)
+
+var imageSortKeys = map[string]bool{"created_at": true, "file_size": true, "name": true}
+
+// imageFilterParams are the query params getImageFilters reads
+var imageFilterParams = []string{
+	"limit", "offset", "content_type", "name", "tag", "album", "attribute",
+	"min_width", "max_width", "min_height", "max_height", "min_size", "max_size",
+	"created_after", "created_before", "sort", "order", "cursor",
+}
+
+// hasImageFilters reports whether the request sets any of the params of getImageFilters
+func hasImageFilters(r *http.Request) bool {
+	query := r.URL.Query()
+	for _, key := range imageFilterParams {
+		if query.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern so that the name filter is a plain substring match
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// imageCursor is the position of the last image of a page , the sort it was created with is kept so that it can't be reused with another one
+type imageCursor struct {
+	SortKey    string `json:"k"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ImageID    int64  `json:"id"`
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// imageListParams are the filters of CountOrgImages with the sort , cursor and page of the list
+type imageListParams struct {
+	database.CountOrgImagesParams
+	SortKey         string
+	Descending      bool
+	CursorID        sql.NullInt64
+	CursorFileSize  sql.NullInt64
+	CursorName      sql.NullString
+	CursorCreatedAt sql.NullTime
+	PageLimit       int32
+	PageOffset      int32
+}
+
+// listOrgImages runs the list query of the sort key and order , each has its own so that postgres can use the (org_id , column , image_id) indexes
+func listOrgImages(ctx context.Context, store *database.Store, params imageListParams) ([]database.Image, error) {
+	filters := params.CountOrgImagesParams
+	switch params.SortKey {
+	case "file_size":
+		arg := database.ListOrgImagesByFileSizeAscParams{
+			OrgID:          filters.OrgID,
+			ContentType:    filters.ContentType,
+			MinWidth:       filters.MinWidth,
+			MaxWidth:       filters.MaxWidth,
+			MinHeight:      filters.MinHeight,
+			MaxHeight:      filters.MaxHeight,
+			MinSize:        filters.MinSize,
+			MaxSize:        filters.MaxSize,
+			CreatedAfter:   filters.CreatedAfter,
+			CreatedBefore:  filters.CreatedBefore,
+			Name:           filters.Name,
+			Tag:            filters.Tag,
+			Attributes:     filters.Attributes,
+			AlbumID:        filters.AlbumID,
+			CursorID:       params.CursorID,
+			CursorFileSize: params.CursorFileSize,
+			PageLimit:      params.PageLimit,
+			PageOffset:     params.PageOffset,
+		}
+		if params.Descending {
+			return store.ListOrgImagesByFileSizeDesc(ctx, database.ListOrgImagesByFileSizeDescParams(arg))
+		}
+		return store.ListOrgImagesByFileSizeAsc(ctx, arg)
+	case "name":
+		arg := database.ListOrgImagesByNameAscParams{
+			OrgID:         filters.OrgID,
+			ContentType:   filters.ContentType,
+			MinWidth:      filters.MinWidth,
+			MaxWidth:      filters.MaxWidth,
+			MinHeight:     filters.MinHeight,
+			MaxHeight:     filters.MaxHeight,
+			MinSize:       filters.MinSize,
+			MaxSize:       filters.MaxSize,
+			CreatedAfter:  filters.CreatedAfter,
+			CreatedBefore: filters.CreatedBefore,
+			Name:          filters.Name,
+			Tag:           filters.Tag,
+			Attributes:    filters.Attributes,
+			AlbumID:       filters.AlbumID,
+			CursorID:      params.CursorID,
+			CursorName:    params.CursorName,
+			PageLimit:     params.PageLimit,
+			PageOffset:    params.PageOffset,
+		}
+		if params.Descending {
+			return store.ListOrgImagesByNameDesc(ctx, database.ListOrgImagesByNameDescParams(arg))
+		}
+		return store.ListOrgImagesByNameAsc(ctx, arg)
+	default:
+		arg := database.ListOrgImagesByCreatedAtAscParams{
+			OrgID:           filters.OrgID,
+			ContentType:     filters.ContentType,
+			MinWidth:        filters.MinWidth,
+			MaxWidth:        filters.MaxWidth,
+			MinHeight:       filters.MinHeight,
+			MaxHeight:       filters.MaxHeight,
+			MinSize:         filters.MinSize,
+			MaxSize:         filters.MaxSize,
+			CreatedAfter:    filters.CreatedAfter,
+			CreatedBefore:   filters.CreatedBefore,
+			Name:            filters.Name,
+			Tag:             filters.Tag,
+			Attributes:      filters.Attributes,
+			AlbumID:         filters.AlbumID,
+			CursorID:        params.CursorID,
+			CursorCreatedAt: params.CursorCreatedAt,
+			PageLimit:       params.PageLimit,
+			PageOffset:      params.PageOffset,
+		}
+		if params.Descending {
+			return store.ListOrgImagesByCreatedAtDesc(ctx, database.ListOrgImagesByCreatedAtDescParams(arg))
+		}
+		return store.ListOrgImagesByCreatedAtAsc(ctx, arg)
+	}
+}
+
+// encodeImageCursor returns the opaque cursor that continues the list after image
+func encodeImageCursor(params imageListParams, image database.Image) string {
+	cursor := imageCursor{SortKey: params.SortKey, Descending: params.Descending, ImageID: image.ImageID}
+	switch params.SortKey {
+	case "file_size":
+		cursor.Value = strconv.FormatInt(image.FileSize, 10)
+	case "name":
+		cursor.Value = image.FileName
+	default:
+		cursor.Value = image.CreatedAt.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// applyImageCursor decodes the cursor into the keyset params of the list query
+func applyImageCursor(params *imageListParams, encoded string) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidCursor
+	}
+	var cursor imageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return errInvalidCursor
+	}
+	if cursor.SortKey != params.SortKey || cursor.Descending != params.Descending {
+		return errors.New("the cursor was created with a different sort or order")
+	}
+	switch cursor.SortKey {
+	case "file_size":
+		fileSize, err := strconv.ParseInt(cursor.Value, 10, 64)
+		if err != nil {
+			return errInvalidCursor
+		}
+		params.CursorFileSize = sql.NullInt64{Int64: fileSize, Valid: true}
+	case "name":
+		params.CursorName = sql.NullString{String: cursor.Value, Valid: true}
+	default:
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return errInvalidCursor
+		}
+		params.CursorCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+	}
+	params.CursorID = sql.NullInt64{Int64: cursor.ImageID, Valid: true}
+	// the cursor replaces the offset
+	params.PageOffset = 0
+	return nil
+}
+
+// nextPageLink is the RFC 8288 Link header value pointing at the page after cursor
+func nextPageLink(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", cursor)
+	return fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode())
+}
+
+// getImageFilters reads the filters , sort and page of the image list from the query params ,
+// e.g ?content_type=image/png&min_width=800&created_after=2024-01-01T00:00:00Z&name=beach&tag=holiday&album=3&attribute=camera:x100&sort=file_size&order=desc
+func getImageFilters(r *http.Request, orgId int64) (imageListParams, error) {
+	query := r.URL.Query()
+	params := imageListParams{
+		CountOrgImagesParams: database.CountOrgImagesParams{
+			OrgID: orgId,
+		},
+		SortKey:    "created_at",
+		PageLimit:  defaultImagesLimit,
+		PageOffset: 0,
+	}
+
+	// a bad limit or offset falls back to the default like it always has
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		params.PageLimit = int32(min(limit, maxImagesLimit))
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		params.PageOffset = int32(offset)
+	}
+
+	if contentType := query.Get("content_type"); contentType != "" {
+		params.ContentType = sql.NullString{String: contentType, Valid: true}
+	}
+	if name := query.Get("name"); name != "" {
+		params.Name = sql.NullString{String: likeEscaper.Replace(name), Valid: true}
+	}
+	if tag := normalizeTag(query.Get("tag")); tag != "" {
+		params.Tag = sql.NullString{String: tag, Valid: true}
+	}
+	if album := query.Get("album"); album != "" {
+		albumId, err := strconv.ParseInt(album, 10, 64)
+		if err != nil {
+			return params, errors.New("album must be an album id")
+		}
+		params.AlbumID = sql.NullInt64{Int64: albumId, Valid: true}
+	}
+	// attribute=key:value can be repeated , images have to match all of them
+	if values := query["attribute"]; len(values) > 0 {
+		attributes := map[string]string{}
+		for _, value := range values {
+			key, attributeValue, found := strings.Cut(value, ":")
+			if !found || key == "" {
+				return params, errors.New("attribute filters must look like key:value")
+			}
+			attributes[key] = attributeValue
+		}
+		encoded, err := json.Marshal(attributes)
+		if err != nil {
+			return params, err
+		}
+		params.Attributes = pqtype.NullRawMessage{RawMessage: encoded, Valid: true}
+	}
+	for key, target := range map[string]*sql.NullInt32{
+		"min_width":  &params.MinWidth,
+		"max_width":  &params.MaxWidth,
+		"min_height": &params.MinHeight,
+		"max_height": &params.MaxHeight,
+	} {
+		if value := query.Get(key); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil || parsed < 0 {
+				return params, fmt.Errorf("%s must be a positive number", key)
+			}
+			*target = sql.NullInt32{Int32: int32(parsed), Valid: true}
+		}
+	}
+	for key, target := range map[string]*sql.NullInt64{
+		"min_size": &params.MinSize,
+		"max_size": &params.MaxSize,
+	} {
+		if value := query.Get(key); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 0 {
+				return params, fmt.Errorf("%s must be a positive number of bytes", key)
+			}
+			*target = sql.NullInt64{Int64: parsed, Valid: true}
+		}
+	}
+	for key, target := range map[string]*sql.NullTime{
+		"created_after":  &params.CreatedAfter,
+		"created_before": &params.CreatedBefore,
+	} {
+		if value := query.Get(key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return params, fmt.Errorf("%s must be an RFC 3339 timestamp", key)
+			}
+			*target = sql.NullTime{Time: parsed, Valid: true}
+		}
+	}
+
+	if sortKey := query.Get("sort"); sortKey != "" {
+		if !imageSortKeys[sortKey] {
+			return params, fmt.Errorf("unknown sort key %q , use created_at , file_size or name", sortKey)
+		}
+		params.SortKey = sortKey
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		params.Descending = true
+	default:
+		return params, errors.New("the order must be asc or desc")
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		if err := applyImageCursor(&params, cursor); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
@@ -1,23 +1,26 @@
 package server
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"image"
-	"mime/multipart"
+	"io"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
-
-	_ "image/jpeg"
-	_ "image/png"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/blobs"
 	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
 	"github.com/mbeka02/image-service/internal/imgproc"
 	"github.com/mbeka02/image-service/internal/imgstore"
 	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/trash"
+	"github.com/mbeka02/image-service/internal/webhook"
 	"github.com/sqlc-dev/pqtype"
 )
 
@@ -27,24 +30,18 @@ type ImageHandler struct {
 	Store          *database.Store
 	FileStorage    imgstore.Storage
 	ImageProcessor imgproc.ImageProcessor
-}
-type ImageMetadata struct {
-	ContentType string `json:"content_type,omitempty"`
-	Width       int    `json:"width,omitempty"`
-	Height      int    `json:"height,omitempty"`
-}
-
-func (i *ImageMetadata) Value() ([]byte, error) {
-	return json.Marshal(i)
-}
-
-func (i *ImageMetadata) Scan(value interface{}) error {
-	b, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
-	}
-
-	return json.Unmarshal(b, &i)
+	Webhooks       *webhook.Dispatcher
+	Fetcher        *fetch.Fetcher
+	PresignExpiry  time.Duration
+	DownloadExpiry time.Duration
+	// GPSPolicy is one of the imgproc GPS policies , it is applied to every image in a response
+	GPSPolicy string
+	// TrashRetention is how long deleted images stay in the trash before they are purged
+	TrashRetention time.Duration
+	// MaxVersions is how many archived versions are kept for every image , older ones are deleted
+	MaxVersions int
+	// renders bounds the images the public routes process at the same time
+	renders chan struct{}
 }
 
 func (ih *ImageHandler) handleImageUpload(w http.ResponseWriter, r *http.Request) {
@@ -54,51 +51,33 @@ func (ih *ImageHandler) handleImageUpload(w http.ResponseWriter, r *http.Request
 		respondWithError(w, http.StatusBadRequest, fmt.Errorf("bad request:%v", err))
 		return
 	}
-	allowedFileTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-	}
-	metadata, err := extractMetadata(file)
+	defer file.Close()
+	metadata, err := validateImage(file)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, fmt.Errorf("uanble to extract metadata:%v", err))
-		fmt.Println(err)
-		return
-	}
-	if _, ok := allowedFileTypes[metadata.ContentType]; !ok {
-		respondWithError(w, http.StatusBadRequest, fmt.Errorf("invalid file format:%v", metadata.ContentType))
-		return
-	}
-
-	// upload the file to GC storage
-	uploadResponse, err := ih.FileStorage.Upload(r.Context(), fileHeader)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, fmt.Errorf("internal server error : %v", err))
+		respondWithError(w, http.StatusBadRequest, err)
 		return
 	}
-	payload, err := getAuthPayload(r.Context())
+	tenant, err := getTenant(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
-	rawMessage, err := metadata.Value()
+	duplicates, err := getDuplicatePolicy(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, errors.New("failed to get image metadata"))
+		respondWithError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	nullableJSON := pqtype.NullRawMessage{
-		RawMessage: rawMessage,
-		Valid:      true, // Set to false if you want to store NULL
-	}
-	// save to DB
-	createdImage, err := ih.Store.CreateImage(r.Context(), database.CreateImageParams{
-		UserID:     payload.UserID,
-		FileName:   uploadResponse.FileName,
-		StorageUrl: uploadResponse.StorageUrl,
-		FileSize:   uploadResponse.Size,
-		Metadata:   nullableJSON,
-	})
+	createdImage, err := ih.storeImage(r.Context(), tenant.UserID, tenant.OrgID, fileHeader.Filename, metadata, file, duplicates)
 	if err != nil {
+		if errors.Is(err, errDuplicateImage) {
+			respondWithError(w, http.StatusConflict, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := ih.signImage(r.Context(), &createdImage); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -112,71 +91,76 @@ func (ih *ImageHandler) handleImageUpload(w http.ResponseWriter, r *http.Request
 }
 
 func (ih *ImageHandler) handleGetImages(w http.ResponseWriter, r *http.Request) {
-	payload, err := getAuthPayload(r.Context())
+	tenant, err := getTenant(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	// Get limit from query parameter, default to 10 if not provided
-	limitStr := r.URL.Query().Get("limit")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10 // default limit
-	}
-
-	// Get offset from query parameter, default to 0 if not provided
-	offsetStr := r.URL.Query().Get("offset")
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0 // default offset
-	}
-	data, err := ih.Store.GetUserImages(r.Context(), database.GetUserImagesParams{
-		UserID: payload.UserID,
-		Limit:  int32(limit),
-		Offset: int32(offset),
-	})
+	params, err := getImageFilters(r, tenant.OrgID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err)
+		respondWithError(w, http.StatusBadRequest, err)
 		return
 	}
-	response := APIResponse{
-		Status:  http.StatusOK,
-		Data:    data,
-		Message: "images",
+	pagination := &Pagination{}
+	if r.URL.Query().Get("total") == "true" {
+		total, err := ih.Store.CountOrgImages(r.Context(), params.CountOrgImagesParams)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		pagination.Total = &total
 	}
-	respondWithJSON(w, http.StatusOK, response)
-}
-
-func (ih *ImageHandler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
-	imageId, err := getImageId(r)
+	// one extra row tells us whether there is a next page
+	limit := params.PageLimit
+	params.PageLimit++
+	data, err := listOrgImages(r.Context(), ih.Store, params)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
-	payload, err := getAuthPayload(r.Context())
-	if err != nil {
+	if len(data) > int(limit) {
+		data = data[:limit]
+		pagination.NextCursor = encodeImageCursor(params, data[len(data)-1])
+		w.Header().Set("Link", nextPageLink(r, pagination.NextCursor))
+	}
+	if err := ih.signImages(r.Context(), data); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
-	image, err := ih.Store.GetImage(r.Context(), int64(imageId))
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
-		return
+	response := APIResponse{
+		Status:     http.StatusOK,
+		Data:       data,
+		Message:    "images",
+		Pagination: pagination,
 	}
-	if image.UserID != payload.UserID {
-		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (ih *ImageHandler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
 		return
 	}
-	if err = ih.FileStorage.Delete(r.Context(), image.FileName); err != nil {
-		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the image"))
-		return
+	// ?permanent=true skips the trash , only admins can do that
+	if r.URL.Query().Get("permanent") == "true" {
+		if !ih.canPurge(w, r, image) {
+			return
+		}
+		if err := trash.Purge(r.Context(), ih.Store, ih.FileStorage, image); err != nil {
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the image"))
+			return
+		}
+	} else {
+		trashed, err := ih.Store.TrashImage(r.Context(), image.ImageID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the image"))
+			return
+		}
+		image = trashed
 	}
-
-	ih.Store.DeleteUserImage(r.Context(), database.DeleteUserImageParams{
-		UserID:  payload.UserID,
-		ImageID: int64(imageId),
-	})
+	// the webhooks of the owner are notified even when a collaborator deleted the image
+	ih.publish(r.Context(), image.UserID, webhook.EventImageDeleted, image)
 	response := APIResponse{
 		Status:  http.StatusOK,
 		Message: "deleted the image sucessfully",
@@ -186,25 +170,14 @@ func (ih *ImageHandler) handleDeleteImage(w http.ResponseWriter, r *http.Request
 }
 
 func (ih *ImageHandler) handleGetImage(w http.ResponseWriter, r *http.Request) {
-	imageId, err := getImageId(r)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
 		return
 	}
-	payload, err := getAuthPayload(r.Context())
-	if err != nil {
+	if err := ih.signImage(r.Context(), &image); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
-	image, err := ih.Store.GetImage(r.Context(), int64(imageId))
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
-		return
-	}
-	if image.UserID != payload.UserID {
-		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
-		return
-	}
 	respondWithJSON(w, http.StatusOK, APIResponse{
 		Message: "image:",
 		Data:    image,
@@ -213,23 +186,12 @@ func (ih *ImageHandler) handleGetImage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ih *ImageHandler) handleImageTransformations(w http.ResponseWriter, r *http.Request) {
-	imageId, err := getImageId(r)
-	image, err := ih.Store.GetImage(r.Context(), int64(imageId))
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
-		return
-	}
-	payload, err := getAuthPayload(r.Context())
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err)
-		return
-	}
-	if image.UserID != payload.UserID {
-		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+	image, ok := ih.getAuthorizedImage(w, r, accessTransform)
+	if !ok {
 		return
 	}
 	request := models.TransformationsRequest{}
-	err = parseAndValidateRequest(r, &request)
+	err := parseAndValidateRequest(r, &request)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, err)
 	}
@@ -252,100 +214,180 @@ func (ih *ImageHandler) handleImageTransformations(w http.ResponseWriter, r *htt
 		})
 		return
 	}
+	ih.publish(r.Context(), image.UserID, webhook.EventImageTransformed, map[string]interface{}{
+		"image_id":        image.ImageID,
+		"transformations": request,
+	})
 	respondWithImage(w, fileData)
 }
 
 func (ih *ImageHandler) applyTransformations(imagePath string, request *models.TransformationsRequest) ([]byte, error) {
-	var err error
-	var currentImageData []byte = nil
+	defer os.Remove(imagePath)
 	// Read the initial image
-	currentImageData, err = os.ReadFile(imagePath)
+	currentImageData, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read initial image: %v", err)
 	}
 
-	// Apply transformations in a specific order
-	transformationFuncs := []func() ([]byte, error){
-		func() ([]byte, error) {
-			if request.Resize != nil {
-				return ih.ImageProcessor.Resize(currentImageData, request.Resize.Width, request.Resize.Height)
-			}
-			return currentImageData, nil
-		},
-		func() ([]byte, error) {
-			if request.Rotate != nil {
-				return ih.ImageProcessor.Rotate(currentImageData, request.Rotate.Angle)
-			}
+	return imgproc.Transform(ih.ImageProcessor, currentImageData, request)
+}
 
-			return currentImageData, nil
-		},
-		func() ([]byte, error) {
-			if request.Crop != nil {
-				return ih.ImageProcessor.Crop(currentImageData, request.Crop.Width, request.Crop.Height)
-			}
+var allowedFileTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
 
-			return currentImageData, nil
-		},
-		func() ([]byte, error) {
-			if request.Flip != nil {
-				return ih.ImageProcessor.Flip(currentImageData)
-			}
-			return currentImageData, nil
-		},
-		func() ([]byte, error) {
-			if request.Convert != nil {
-				return ih.ImageProcessor.Convert(currentImageData, request.Convert.ImageType)
-			}
-			return currentImageData, nil
-		},
-		func() ([]byte, error) {
-			if request.Zoom != nil {
-				return ih.ImageProcessor.Zoom(currentImageData, request.Zoom.Factor)
-			}
-			return currentImageData, nil
-		},
+// sniffLen is how much of a file http.DetectContentType looks at
+const sniffLen = 512
+
+// validateImageHeader rejects a file whose first bytes don't match one of the allowed formats , it lets an upload fail before all of it arrives
+func validateImageHeader(header []byte) error {
+	if contentType := http.DetectContentType(header); !allowedFileTypes[contentType] {
+		return fmt.Errorf("invalid file format:%v", contentType)
+	}
+	return nil
+}
+
+// validateImage extracts the metadata of an upload and makes sure it is in one of the allowed formats
+func validateImage(file io.ReadSeeker) (*imgproc.ImageMetadata, error) {
+	metadata, err := imgproc.ExtractMetadata(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract metadata:%v", err)
+	}
+	if _, ok := allowedFileTypes[metadata.ContentType]; !ok {
+		return nil, fmt.Errorf("invalid file format:%v", metadata.ContentType)
 	}
+	return metadata, nil
+}
 
-	defer os.Remove(imagePath)
-	// Apply transformations sequentially
-	for _, transformFunc := range transformationFuncs {
+const (
+	// duplicatesAllow stores another image that shares the object of the existing one
+	duplicatesAllow = "allow"
+	// duplicatesReject fails the upload with errDuplicateImage
+	duplicatesReject = "reject"
+	// duplicatesLink returns the existing image instead of creating a new one
+	duplicatesLink = "link"
+)
 
-		// Apply transformation
-		currentImageData, err = transformFunc()
-		if err != nil {
-			return nil, fmt.Errorf("transformation failed: %v", err)
+var errDuplicateImage = errors.New("duplicate image")
+
+// getDuplicatePolicy reads the duplicates query param , it decides what happens when the user already has an image with the same content
+func getDuplicatePolicy(r *http.Request) (string, error) {
+	switch policy := r.URL.Query().Get("duplicates"); policy {
+	case "":
+		return duplicatesAllow, nil
+	case duplicatesAllow, duplicatesReject, duplicatesLink:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid duplicates param:%s", policy)
+	}
+}
+
+// storeImage uploads a validated image to the storage prefix of the organization , saves it to the DB and publishes the uploaded event
+func (ih *ImageHandler) storeImage(ctx context.Context, userId, orgId int64, fileName string, metadata *imgproc.ImageMetadata, src io.Reader, duplicates string) (database.Image, error) {
+	uploadResponse, err := ih.FileStorage.UploadStream(ctx, imgstore.OrgPrefix(orgId)+fileName, metadata.ContentType, src)
+	if err != nil {
+		return database.Image{}, fmt.Errorf("internal server error : %v", err)
+	}
+	return ih.saveImage(ctx, userId, orgId, uploadResponse, metadata, duplicates)
+}
+
+// saveImage creates the row for an object that is already in storage and publishes the uploaded event ,
+// the object is deleted when its content is already stored
+func (ih *ImageHandler) saveImage(ctx context.Context, userId, orgId int64, uploadResponse *imgstore.UploadResponse, metadata *imgproc.ImageMetadata, duplicates string) (database.Image, error) {
+	contentHash := sql.NullString{String: uploadResponse.ContentHash, Valid: true}
+	if duplicates != duplicatesAllow {
+		existingImage, err := ih.Store.GetOrgImageByHash(ctx, database.GetOrgImageByHashParams{
+			OrgID:       orgId,
+			ContentHash: contentHash,
+		})
+		if err == nil {
+			ih.FileStorage.Delete(ctx, uploadResponse.FileName)
+			existingImage.Metadata = ih.redactMetadata(existingImage.Metadata)
+			if duplicates == duplicatesReject {
+				return existingImage, fmt.Errorf("%w:image %d has the same content", errDuplicateImage, existingImage.ImageID)
+			}
+			return existingImage, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			ih.FileStorage.Delete(ctx, uploadResponse.FileName)
+			return database.Image{}, fmt.Errorf("unable to check for duplicates:%v", err)
 		}
 	}
+	uploadResponse, err := blobs.Acquire(ctx, ih.Store, ih.FileStorage, orgId, uploadResponse)
+	if err != nil {
+		return database.Image{}, err
+	}
 
-	return currentImageData, nil
+	rawMessage, err := metadata.Value()
+	if err != nil {
+		return database.Image{}, errors.New("failed to get image metadata")
+	}
+
+	nullableJSON := pqtype.NullRawMessage{
+		RawMessage: rawMessage,
+		Valid:      true, // Set to false if you want to store NULL
+	}
+	// save to DB
+	createdImage, err := ih.Store.CreateImage(ctx, database.CreateImageParams{
+		UserID:      userId,
+		OrgID:       orgId,
+		FileName:    uploadResponse.FileName,
+		StorageUrl:  uploadResponse.StorageUrl,
+		FileSize:    uploadResponse.Size,
+		Metadata:    nullableJSON,
+		ContentHash: contentHash,
+		Phash:       metadata.NullPerceptualHash(),
+	})
+	if err != nil {
+		blobs.Release(ctx, ih.Store, ih.FileStorage, orgId, uploadResponse.ContentHash)
+		return database.Image{}, err
+	}
+	createdImage.Metadata = ih.redactMetadata(createdImage.Metadata)
+	ih.publish(ctx, userId, webhook.EventImageUploaded, createdImage)
+	return createdImage, nil
 }
 
-func getImageId(r *http.Request) (int, error) {
-	idParam := chi.URLParam(r, "imageId")
-	imageId, err := strconv.Atoi(idParam)
+// signImage replaces the storage url of the image with a signed url that expires after DownloadExpiry and applies the GPS policy to its metadata
+func (ih *ImageHandler) signImage(ctx context.Context, image *database.Image) error {
+	url, err := ih.FileStorage.SignedURL(ctx, image.FileName, ih.DownloadExpiry)
 	if err != nil {
-		return 0, errors.New("invalid url param")
+		return err
 	}
-	return imageId, nil
+	image.StorageUrl = url
+	image.Metadata = ih.redactMetadata(image.Metadata)
+	return nil
 }
 
-func extractMetadata(file multipart.File) (*ImageMetadata, error) {
-	buff := make([]byte, 512)
-	if _, err := file.Read(buff); err != nil {
-		return nil, err
+func (ih *ImageHandler) signImages(ctx context.Context, images []database.Image) error {
+	for i := range images {
+		if err := ih.signImage(ctx, &images[i]); err != nil {
+			return err
+		}
 	}
-	contentType := http.DetectContentType(buff)
-	file.Seek(0, 0)
+	return nil
+}
 
-	config, _, err := image.DecodeConfig(file)
-	if err != nil {
-		return nil, err
+func (ih *ImageHandler) redactMetadata(metadata pqtype.NullRawMessage) pqtype.NullRawMessage {
+	if !metadata.Valid {
+		return metadata
 	}
+	metadata.RawMessage = imgproc.RedactGPS(metadata.RawMessage, ih.GPSPolicy)
+	return metadata
+}
 
-	file.Seek(0, 0)
-	return &ImageMetadata{
-		ContentType: contentType,
-		Height:      config.Height,
-		Width:       config.Width,
-	}, nil
+// publish queues a webhook event , failures are only logged so that they never fail the request
+func (ih *ImageHandler) publish(ctx context.Context, userId int64, event string, data interface{}) {
+	if err := ih.Webhooks.Publish(ctx, userId, event, data); err != nil {
+		log.Printf("unable to publish %s:%v", event, err)
+	}
+}
+
+func getImageId(r *http.Request) (int, error) {
+	idParam := chi.URLParam(r, "imageId")
+	imageId, err := strconv.Atoi(idParam)
+	if err != nil {
+		return 0, errors.New("invalid url param")
+	}
+	return imageId, nil
 }
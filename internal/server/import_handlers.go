@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/mbeka02/image-service/internal/fetch"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+// handleImportImage fetches an image from a remote url and stores it the same way as handleImageUpload
+func (ih *ImageHandler) handleImportImage(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	duplicates, err := getDuplicatePolicy(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	request := models.ImportImageRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := ih.Fetcher.Fetch(r.Context(), request.Url)
+	if err != nil {
+		switch {
+		case errors.Is(err, fetch.ErrForbiddenAddress), errors.Is(err, fetch.ErrUnsupportedURL), errors.Is(err, fetch.ErrTooManyRedirects):
+			respondWithError(w, http.StatusBadRequest, err)
+		case errors.Is(err, fetch.ErrTooLarge):
+			respondWithError(w, http.StatusRequestEntityTooLarge, err)
+		default:
+			respondWithError(w, http.StatusBadGateway, err)
+		}
+		return
+	}
+	file := bytes.NewReader(result.Data)
+	metadata, err := validateImage(file)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	createdImage, err := ih.storeImage(r.Context(), tenant.UserID, tenant.OrgID, result.FileName, metadata, file, duplicates)
+	if err != nil {
+		if errors.Is(err, errDuplicateImage) {
+			respondWithError(w, http.StatusConflict, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := ih.signImage(r.Context(), &createdImage); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Data:    createdImage,
+		Message: "imported",
+	})
+}
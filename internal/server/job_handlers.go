@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/jobs"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+type JobHandler struct {
+	Store       *database.Store
+	MaxAttempts int32
+	// Images signs the url of the result image
+	Images *ImageHandler
+}
+
+func (jh *JobHandler) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	imageId, err := getImageId(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the output is saved in the active organization , not in the one of the image
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	image, err := jh.Store.GetImage(r.Context(), int64(imageId))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+		return
+	}
+	level, err := imageAccess(r.Context(), jh.Store, payload.UserID, image)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to check the image permissions"))
+		return
+	}
+	if level < accessTransform {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return
+	}
+	request := models.TransformationsRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	spec, err := json.Marshal(request)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to encode the job spec"))
+		return
+	}
+	job, err := jh.Store.CreateJob(r.Context(), database.CreateJobParams{
+		UserID:      payload.UserID,
+		OrgID:       tenant.OrgID,
+		ImageID:     image.ImageID,
+		Spec:        spec,
+		MaxAttempts: jh.MaxAttempts,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to queue the job"))
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, APIResponse{
+		Status:  http.StatusAccepted,
+		Message: "job queued",
+		Data:    job,
+	})
+}
+
+func (jh *JobHandler) handleGetJobs(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10 // default limit
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // default offset
+	}
+	data, err := jh.Store.GetUserJobs(r.Context(), database.GetUserJobsParams{
+		UserID: payload.UserID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Data:    data,
+		Message: "jobs",
+	})
+}
+
+func (jh *JobHandler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := jh.getUserJob(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Data:    job,
+		Message: "job:",
+	})
+}
+
+func (jh *JobHandler) handleGetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := jh.getUserJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != jobs.StatusCompleted || !job.ResultImageID.Valid {
+		respondWithError(w, http.StatusConflict, errors.New("the job has not completed: "+job.Status))
+		return
+	}
+	image, err := jh.Store.GetImage(r.Context(), job.ResultImageID.Int64)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the result image"))
+		return
+	}
+	if err := jh.Images.signImage(r.Context(), &image); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Data:    image,
+		Message: "result:",
+	})
+}
+
+// getUserJob loads the job in the url and makes sure it belongs to the caller , it writes the error response itself
+func (jh *JobHandler) getUserJob(w http.ResponseWriter, r *http.Request) (database.Job, bool) {
+	jobId, err := strconv.ParseInt(chi.URLParam(r, "jobId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Job{}, false
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Job{}, false
+	}
+	job, err := jh.Store.GetJob(r.Context(), jobId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errors.New("unable to get job"))
+		return database.Job{}, false
+	}
+	if job.UserID != payload.UserID {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return database.Job{}, false
+	}
+	return job, true
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/mbeka02/image-service/internal/auth"
+)
+
+// handleGetJWKS publishes the public keys access tokens are signed with so that other services can verify them ,
+// the set is empty when the tokens use a symmetric key
+func (uh *UserHandler) handleGetJWKS(w http.ResponseWriter, r *http.Request) {
+	keys := auth.JWKS{Keys: []auth.JWK{}}
+	if publisher, ok := uh.AuthMaker.(auth.KeyPublisher); ok {
+		keys = publisher.JWKS()
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, keys)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/jpeg"
+	"net/http"
+	"strconv"
+
+	"github.com/mbeka02/image-service/internal/imgproc"
+)
+
+const (
+	defaultPlaceholderWidth = 32
+	maxPlaceholderWidth     = 128
+)
+
+// handleGetImageMetadata returns the metadata that was extracted when the image was uploaded , the GPS policy is applied to it
+func (ih *ImageHandler) handleGetImageMetadata(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	metadata := ih.redactMetadata(image.Metadata)
+	if !metadata.Valid {
+		metadata.RawMessage = json.RawMessage("{}")
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "image metadata",
+		Data:    metadata.RawMessage,
+	})
+}
+
+// handleGetImagePlaceholder renders the BlurHash of the image as a tiny blurred jpeg , the width query param sets its size and the height follows the aspect ratio
+func (ih *ImageHandler) handleGetImagePlaceholder(w http.ResponseWriter, r *http.Request) {
+	width, err := strconv.Atoi(r.URL.Query().Get("width"))
+	if err != nil || width <= 0 {
+		width = defaultPlaceholderWidth
+	}
+	if width > maxPlaceholderWidth {
+		width = maxPlaceholderWidth
+	}
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	var metadata imgproc.ImageMetadata
+	if image.Metadata.Valid {
+		if err := json.Unmarshal(image.Metadata.RawMessage, &metadata); err != nil {
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to read the image metadata"))
+			return
+		}
+	}
+	if metadata.BlurHash == "" || metadata.Width == 0 || metadata.Height == 0 {
+		respondWithError(w, http.StatusConflict, errors.New("the image does not have a placeholder yet"))
+		return
+	}
+	height := max(1, width*metadata.Height/metadata.Width)
+	if height > maxPlaceholderWidth {
+		height = maxPlaceholderWidth
+	}
+	placeholder, err := imgproc.DecodeBlurHash(metadata.BlurHash, width, height)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var data bytes.Buffer
+	if err := jpeg.Encode(&data, placeholder, &jpeg.Options{Quality: 80}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// the placeholder only changes if the image is replaced
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	respondWithImage(w, data.Bytes())
+}
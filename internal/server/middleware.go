@@ -2,11 +2,14 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
 )
 
 type contextKey string
@@ -14,6 +17,7 @@ type contextKey string
 const (
 	authorizationTypeBearer            = "bearer"
 	authorizationPayloadKey contextKey = "authorization_payload"
+	tenantKey               contextKey = "tenant"
 )
 
 var (
@@ -21,6 +25,7 @@ var (
 	ErrMalformedAuth   = errors.New("malformed authorization header")
 	ErrUnsupportedAuth = errors.New("unsupported authorization type")
 	ErrInvalidPayload  = errors.New("invalid authorization payload")
+	ErrNotAMember      = errors.New("you are not a member of the organization")
 )
 
 func getAuthPayload(ctx context.Context) (*auth.Payload, error) {
@@ -31,6 +36,15 @@ func getAuthPayload(ctx context.Context) (*auth.Payload, error) {
 	return payload, nil
 }
 
+// getTenant returns the membership of the user in the organization they are working in
+func getTenant(ctx context.Context) (database.OrgMember, error) {
+	member, ok := ctx.Value(tenantKey).(database.OrgMember)
+	if !ok {
+		return database.OrgMember{}, ErrInvalidPayload
+	}
+	return member, nil
+}
+
 func extractAndVerifyToken(r *http.Request, maker auth.Maker) (*auth.Payload, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -65,3 +79,55 @@ func AuthMiddleware(maker auth.Maker) func(next http.Handler) http.Handler {
 		})
 	}
 }
+
+// TenantMiddleware loads the membership of the user in the organization of the token , it has to run after AuthMiddleware.
+// tokens without an organization use the personal one
+func TenantMiddleware(store *database.Store) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload, err := getAuthPayload(r.Context())
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err)
+				return
+			}
+			orgId := payload.OrgID
+			if orgId == 0 {
+				org, err := store.GetPersonalOrganization(r.Context(), sql.NullInt64{Int64: payload.UserID, Valid: true})
+				if err != nil {
+					respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the personal organization"))
+					return
+				}
+				orgId = org.OrgID
+			}
+			member, err := store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: orgId, UserID: payload.UserID})
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					respondWithError(w, http.StatusUnauthorized, ErrNotAMember)
+					return
+				}
+				respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the membership"))
+				return
+			}
+			ctx := context.WithValue(r.Context(), tenantKey, member)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole rejects users whose role in the organization is below the minimum , it has to run after TenantMiddleware
+func RequireRole(minimum string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			member, err := getTenant(r.Context())
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if roleRanks[member.Role] < roleRanks[minimum] {
+				respondWithError(w, http.StatusForbidden, fmt.Errorf("the %s role can't do this", member.Role))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+type OrgHandler struct {
+	Store               *database.Store
+	AuthMaker           auth.Maker
+	AccessTokenDuration time.Duration
+}
+
+// handleGetOrganizations lists the organizations the user is a member of with their role , the personal one comes first
+func (oh *OrgHandler) handleGetOrganizations(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	orgs, err := oh.Store.GetUserOrganizations(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the organizations"))
+		return
+	}
+	if orgs == nil {
+		orgs = []database.GetUserOrganizationsRow{}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "organizations",
+		Data:    orgs,
+	})
+}
+
+func (oh *OrgHandler) handleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.CreateOrganizationRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	// an organization without its owner couldn't be managed by anyone
+	var org database.Organization
+	err = oh.Store.ExecTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		org, err = q.CreateOrganization(r.Context(), database.CreateOrganizationParams{Name: request.Name})
+		if err != nil {
+			return err
+		}
+		_, err = q.AddOrgMember(r.Context(), database.AddOrgMemberParams{
+			OrgID:  org.OrgID,
+			UserID: payload.UserID,
+			Role:   roleOwner,
+		})
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the organization"))
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, APIResponse{
+		Status:  http.StatusCreated,
+		Message: "organization created",
+		Data:    org,
+	})
+}
+
+func (oh *OrgHandler) handleUpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := oh.getMembership(w, r, roleAdmin)
+	if !ok {
+		return
+	}
+	request := models.UpdateOrganizationRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	org, err := oh.Store.UpdateOrganization(r.Context(), database.UpdateOrganizationParams{
+		OrgID: org.OrgID,
+		Name:  request.Name,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to update the organization"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "organization updated",
+		Data:    org,
+	})
+}
+
+// handleDeleteOrganization deletes an organization without images , personal organizations can't be deleted
+func (oh *OrgHandler) handleDeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := oh.getMembership(w, r, roleOwner)
+	if !ok {
+		return
+	}
+	if org.PersonalUserID.Valid {
+		respondWithError(w, http.StatusBadRequest, errors.New("a personal organization can't be deleted"))
+		return
+	}
+	deleted, err := oh.Store.DeleteOrganization(r.Context(), org.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the organization"))
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusConflict, errors.New("the organization still has images"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "organization deleted",
+	})
+}
+
+// handleSwitchOrganization returns an access token for the organization , lists and uploads made with it are scoped to the organization
+func (oh *OrgHandler) handleSwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := oh.getMembership(w, r, roleViewer)
+	if !ok {
+		return
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	token, err := oh.AuthMaker.Create(payload.Email, payload.UserID, org.OrgID, oh.AccessTokenDuration)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the access token"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "switched organization",
+		Data: models.SwitchOrganizationResponse{
+			AccessToken:  token,
+			Organization: org,
+		},
+	})
+}
+
+func (oh *OrgHandler) handleGetMembers(w http.ResponseWriter, r *http.Request) {
+	org, _, ok := oh.getMembership(w, r, roleViewer)
+	if !ok {
+		return
+	}
+	members, err := oh.Store.GetOrgMembers(r.Context(), org.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the members"))
+		return
+	}
+	if members == nil {
+		members = []database.GetOrgMembersRow{}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "members",
+		Data:    members,
+	})
+}
+
+// handleAddMember adds a user or changes their role , only owners can make other users owners or change the role of an owner
+func (oh *OrgHandler) handleAddMember(w http.ResponseWriter, r *http.Request) {
+	org, membership, ok := oh.getMembership(w, r, roleAdmin)
+	if !ok {
+		return
+	}
+	if org.PersonalUserID.Valid {
+		respondWithError(w, http.StatusBadRequest, errors.New("a personal organization can't have other members"))
+		return
+	}
+	request := models.AddMemberRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	user, err := oh.Store.GetUserByEmail(r.Context(), request.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("user not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the user"))
+		return
+	}
+	current, err := oh.Store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: org.OrgID, UserID: user.UserID})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the membership"))
+		return
+	}
+	if membership.Role != roleOwner && (request.Role == roleOwner || current.Role == roleOwner) {
+		respondWithError(w, http.StatusForbidden, errors.New("only owners can manage owners"))
+		return
+	}
+	if current.Role == roleOwner && request.Role != roleOwner {
+		if ok := oh.keepsAnOwner(w, r, org.OrgID); !ok {
+			return
+		}
+	}
+	member, err := oh.Store.AddOrgMember(r.Context(), database.AddOrgMemberParams{
+		OrgID:  org.OrgID,
+		UserID: user.UserID,
+		Role:   request.Role,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to add the member"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "member saved",
+		Data:    member,
+	})
+}
+
+// handleRemoveMember removes a member , admins can remove members and viewers and every member can leave
+func (oh *OrgHandler) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	org, membership, ok := oh.getMembership(w, r, roleViewer)
+	if !ok {
+		return
+	}
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	if org.PersonalUserID.Valid && org.PersonalUserID.Int64 == userId {
+		respondWithError(w, http.StatusBadRequest, errors.New("you can't leave your personal organization"))
+		return
+	}
+	member, err := oh.Store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: org.OrgID, UserID: userId})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("member not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the membership"))
+		return
+	}
+	if userId != membership.UserID {
+		if roleRanks[membership.Role] < roleRanks[roleAdmin] || (member.Role == roleOwner && membership.Role != roleOwner) {
+			respondWithError(w, http.StatusForbidden, errors.New("you can't remove this member"))
+			return
+		}
+	}
+	if member.Role == roleOwner {
+		if ok := oh.keepsAnOwner(w, r, org.OrgID); !ok {
+			return
+		}
+	}
+	if _, err := oh.Store.RemoveOrgMember(r.Context(), database.RemoveOrgMemberParams{OrgID: org.OrgID, UserID: userId}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to remove the member"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "member removed",
+	})
+}
+
+// keepsAnOwner writes the error response and returns false when the organization would be left without an owner
+func (oh *OrgHandler) keepsAnOwner(w http.ResponseWriter, r *http.Request, orgId int64) bool {
+	owners, err := oh.Store.CountOrgOwners(r.Context(), orgId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to count the owners"))
+		return false
+	}
+	if owners <= 1 {
+		respondWithError(w, http.StatusBadRequest, errors.New("the organization needs at least one owner"))
+		return false
+	}
+	return true
+}
+
+// getMembership loads the organization in the url and the membership of the user in it ,
+// it writes the error response and returns false when the user isn't a member with at least the minimum role
+func (oh *OrgHandler) getMembership(w http.ResponseWriter, r *http.Request, minimum string) (database.Organization, database.OrgMember, bool) {
+	orgId, err := strconv.ParseInt(chi.URLParam(r, "orgId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Organization{}, database.OrgMember{}, false
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Organization{}, database.OrgMember{}, false
+	}
+	membership, err := oh.Store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: orgId, UserID: payload.UserID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("organization not found"))
+			return database.Organization{}, database.OrgMember{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the membership"))
+		return database.Organization{}, database.OrgMember{}, false
+	}
+	if roleRanks[membership.Role] < roleRanks[minimum] {
+		respondWithError(w, http.StatusForbidden, fmt.Errorf("the %s role can't do this", membership.Role))
+		return database.Organization{}, database.OrgMember{}, false
+	}
+	org, err := oh.Store.GetOrganization(r.Context(), orgId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the organization"))
+		return database.Organization{}, database.OrgMember{}, false
+	}
+	return org, membership, true
+}
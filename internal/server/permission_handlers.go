@@ -0,0 +1,250 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+func (ih *ImageHandler) handleGetImagePermissions(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	permissions, err := ih.Store.GetImagePermissions(r.Context(), sql.NullInt64{Int64: image.ImageID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the permissions"))
+		return
+	}
+	if permissions == nil {
+		permissions = []database.GetImagePermissionsRow{}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permissions",
+		Data:    permissions,
+	})
+}
+
+func (ih *ImageHandler) handleGrantImagePermission(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	grantee, level, ok := ih.getGrantee(w, r, payload.UserID)
+	if !ok {
+		return
+	}
+	permission, err := ih.Store.GrantImagePermission(r.Context(), database.GrantImagePermissionParams{
+		ImageID:   sql.NullInt64{Int64: image.ImageID, Valid: true},
+		GranteeID: grantee.UserID,
+		GrantedBy: payload.UserID,
+		Level:     level,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to grant the permission"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permission granted",
+		Data:    permission,
+	})
+}
+
+func (ih *ImageHandler) handleRevokeImagePermission(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	granteeId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	revoked, err := ih.Store.RevokeImagePermission(r.Context(), database.RevokeImagePermissionParams{
+		ImageID:   sql.NullInt64{Int64: image.ImageID, Valid: true},
+		GranteeID: granteeId,
+	})
+	ih.respondWithRevoked(w, revoked, err)
+}
+
+func (ih *ImageHandler) handleGetAlbumPermissions(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	permissions, err := ih.Store.GetAlbumPermissions(r.Context(), sql.NullInt64{Int64: album.AlbumID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the permissions"))
+		return
+	}
+	if permissions == nil {
+		permissions = []database.GetAlbumPermissionsRow{}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permissions",
+		Data:    permissions,
+	})
+}
+
+// handleGrantAlbumPermission gives access to the album and every image in it , including images added later
+func (ih *ImageHandler) handleGrantAlbumPermission(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	grantee, level, ok := ih.getGrantee(w, r, payload.UserID)
+	if !ok {
+		return
+	}
+	permission, err := ih.Store.GrantAlbumPermission(r.Context(), database.GrantAlbumPermissionParams{
+		AlbumID:   sql.NullInt64{Int64: album.AlbumID, Valid: true},
+		GranteeID: grantee.UserID,
+		GrantedBy: payload.UserID,
+		Level:     level,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to grant the permission"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permission granted",
+		Data:    permission,
+	})
+}
+
+func (ih *ImageHandler) handleRevokeAlbumPermission(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	granteeId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	revoked, err := ih.Store.RevokeAlbumPermission(r.Context(), database.RevokeAlbumPermissionParams{
+		AlbumID:   sql.NullInt64{Int64: album.AlbumID, Valid: true},
+		GranteeID: granteeId,
+	})
+	ih.respondWithRevoked(w, revoked, err)
+}
+
+// handleGetSharedWithMe lists the images and albums other users have granted the user access to
+func (ih *ImageHandler) handleGetSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	imageRows, err := ih.Store.GetImagesSharedWithUser(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the shared images"))
+		return
+	}
+	albumRows, err := ih.Store.GetAlbumsSharedWithUser(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the shared albums"))
+		return
+	}
+	response := models.SharedWithMeResponse{
+		Images: make([]models.SharedImage, 0, len(imageRows)),
+		Albums: make([]models.SharedAlbum, 0, len(albumRows)),
+	}
+	for _, row := range imageRows {
+		image := database.Image{
+			ImageID:     row.ImageID,
+			UserID:      row.UserID,
+			FileName:    row.FileName,
+			FileSize:    row.FileSize,
+			StorageUrl:  row.StorageUrl,
+			Metadata:    row.Metadata,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			ContentHash: row.ContentHash,
+			Phash:       row.Phash,
+			Attributes:  row.Attributes,
+			Visibility:  row.Visibility,
+		}
+		if err := ih.signImage(r.Context(), &image); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response.Images = append(response.Images, models.SharedImage{Image: image, Level: row.Level})
+	}
+	for _, row := range albumRows {
+		response.Albums = append(response.Albums, models.SharedAlbum{
+			Album: database.Album{
+				AlbumID:      row.AlbumID,
+				UserID:       row.UserID,
+				Title:        row.Title,
+				Description:  row.Description,
+				CoverImageID: row.CoverImageID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+			},
+			Level: row.Level,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "shared with me",
+		Data:    response,
+	})
+}
+
+// getGrantee parses the grant request and looks up the user it is for , it writes the error response and returns false when the grant is invalid
+func (ih *ImageHandler) getGrantee(w http.ResponseWriter, r *http.Request, granterId int64) (database.User, string, bool) {
+	request := models.GrantPermissionRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return database.User{}, "", false
+	}
+	grantee, err := ih.Store.GetUserByEmail(r.Context(), request.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("user not found"))
+			return database.User{}, "", false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the user"))
+		return database.User{}, "", false
+	}
+	if grantee.UserID == granterId {
+		respondWithError(w, http.StatusBadRequest, errors.New("you can't grant a permission to yourself"))
+		return database.User{}, "", false
+	}
+	return grantee, request.Level, true
+}
+
+func (ih *ImageHandler) respondWithRevoked(w http.ResponseWriter, revoked int64, err error) {
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to revoke the permission"))
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("permission not found"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permission revoked",
+	})
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+const maxPresignedSize = 25 << 20
+
+// handlePresignUpload returns a signed url the client can upload the file to without going through the API
+func (ih *ImageHandler) handlePresignUpload(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.PresignUploadRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	// the prefix is how the completion endpoint knows the object belongs to the user and the organization
+	objectName := fmt.Sprintf("%s%s_%d", presignPrefix(tenant), path.Base(request.FileName), time.Now().UnixNano())
+	signedRequest, err := ih.FileStorage.SignedUploadURL(r.Context(), objectName, request.ContentType, maxPresignedSize, ih.PresignExpiry)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "presigned upload",
+		Data: models.PresignUploadResponse{
+			ObjectName: objectName,
+			Method:     signedRequest.Method,
+			Url:        signedRequest.Url,
+			Headers:    signedRequest.Headers,
+			ExpiresAt:  signedRequest.ExpiresAt,
+		},
+	})
+}
+
+// handleCompletePresignedUpload checks the uploaded object and creates the image , objects that are not valid images are deleted
+func (ih *ImageHandler) handleCompletePresignedUpload(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	duplicates, err := getDuplicatePolicy(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	request := models.CompletePresignedUploadRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !strings.HasPrefix(request.ObjectName, presignPrefix(tenant)) {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return
+	}
+	// the object is claimed before anything else so concurrent completions can't both create an image for it ,
+	// the claim is dropped when the completion fails so that it can be retried
+	claimed, err := ih.Store.ClaimPresignedUpload(r.Context(), database.ClaimPresignedUploadParams{
+		ObjectName: request.ObjectName,
+		OrgID:      tenant.OrgID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to complete the upload"))
+		return
+	}
+	if claimed == 0 {
+		respondWithError(w, http.StatusConflict, errors.New("the upload has already been completed"))
+		return
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			ih.Store.ReleasePresignedUpload(context.WithoutCancel(r.Context()), request.ObjectName)
+		}
+	}()
+
+	object, err := ih.FileStorage.Stat(r.Context(), request.ObjectName)
+	if err != nil {
+		if errors.Is(err, imgstore.ErrObjectNotFound) {
+			respondWithError(w, http.StatusNotFound, errors.New("the file has not been uploaded"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if object.Size > maxPresignedSize {
+		ih.FileStorage.Delete(r.Context(), object.FileName)
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the file is larger than %d bytes", maxPresignedSize))
+		return
+	}
+	reader, err := ih.FileStorage.Download(r.Context(), object.FileName)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxPresignedSize))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, fmt.Errorf("unable to read the file:%v", err))
+		return
+	}
+	metadata, err := validateImage(bytes.NewReader(data))
+	if err != nil {
+		ih.FileStorage.Delete(r.Context(), object.FileName)
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// storage only hashes what goes through UploadStream
+	hash := sha256.Sum256(data)
+	object.ContentHash = hex.EncodeToString(hash[:])
+	createdImage, err := ih.saveImage(r.Context(), tenant.UserID, tenant.OrgID, object, metadata, duplicates)
+	if err != nil {
+		if errors.Is(err, errDuplicateImage) {
+			// the object was deleted in favour of the existing image
+			completed = true
+			respondWithError(w, http.StatusConflict, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	completed = true
+	if err := ih.signImage(r.Context(), &createdImage); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Data:    createdImage,
+		Message: "uploaded",
+	})
+}
+
+func presignPrefix(tenant database.OrgMember) string {
+	return fmt.Sprintf("%spresigned/%d/", imgstore.OrgPrefix(tenant.OrgID), tenant.UserID)
+}
@@ -0,0 +1,396 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+const (
+	defaultPublicImagesLimit = 20
+	maxPublicImagesLimit     = 100
+	// maxRenderSize bounds the width and height that can be requested from the public routes
+	maxRenderSize = 4096
+	// maxRenderFileSize bounds the originals the public routes load into memory
+	maxRenderFileSize = 25 << 20
+	// maxConcurrentRenders bounds the images the public routes process at the same time
+	maxConcurrentRenders = 8
+	// shareViewWindow is how long the raw routes of a link keep working after its last counted view ,
+	// it lets the images of the last allowed view load without counting them as views
+	shareViewWindow = 10 * time.Minute
+)
+
+var (
+	errShareLinkExpired = errors.New("this share link has expired or reached its view limit")
+	errRenderTooLarge   = errors.New("the image is too large to be served publicly")
+)
+
+// handleGetPublicImages lists the public images of every user , newest first
+func (ih *ImageHandler) handleGetPublicImages(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPublicImagesLimit
+	}
+	limit = min(limit, maxPublicImagesLimit)
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	images, err := ih.Store.GetPublicImages(r.Context(), database.GetPublicImagesParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get images"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "public images",
+		Data:    ih.publicImages(images, publicImageRawURL),
+	})
+}
+
+func (ih *ImageHandler) handleGetPublicImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getPublicImage(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "image:",
+		Data:    ih.publicImage(image, publicImageRawURL(image)),
+	})
+}
+
+func (ih *ImageHandler) handleGetPublicImageRaw(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getPublicImage(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	ih.renderImage(w, r, image)
+}
+
+// handleGetUnlistedImage returns an unlisted image by its token , the id can't be used since anyone could walk the ids
+func (ih *ImageHandler) handleGetUnlistedImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getUnlistedImage(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "image:",
+		Data:    ih.publicImage(image, fmt.Sprintf("/u/%s/raw", image.UnlistedToken.String)),
+	})
+}
+
+func (ih *ImageHandler) handleGetUnlistedImageRaw(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getUnlistedImage(w, r)
+	if !ok {
+		return
+	}
+	// the token is the only thing keeping the image private so shared caches must not keep it
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	ih.renderImage(w, r, image)
+}
+
+// handleGetShared returns the image or the album behind a share link , this is the only route that counts a view
+func (ih *ImageHandler) handleGetShared(w http.ResponseWriter, r *http.Request) {
+	link, ok := ih.openShareLink(w, r, true)
+	if !ok {
+		return
+	}
+	if link.ImageID.Valid {
+		image, err := ih.Store.GetImage(r.Context(), link.ImageID.Int64)
+		if err != nil {
+			// the image is in the trash
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusNotFound, errors.New("image not found"))
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+			return
+		}
+		respondWithJSON(w, http.StatusOK, APIResponse{
+			Status:  http.StatusOK,
+			Message: "shared image",
+			Data:    ih.publicImage(image, fmt.Sprintf("/s/%s/raw", link.Token)),
+		})
+		return
+	}
+
+	album, err := ih.Store.GetAlbum(r.Context(), link.AlbumID.Int64)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get album"))
+		return
+	}
+	images, err := ih.Store.GetAlbumImages(r.Context(), database.GetAlbumImagesParams{
+		AlbumID: album.AlbumID,
+		Limit:   maxAlbumImagesLimit,
+		Offset:  0,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the album images"))
+		return
+	}
+	data := models.SharedAlbumResponse{Title: album.Title, Description: album.Description}
+	if album.CoverImageID.Valid {
+		data.CoverImageID = &album.CoverImageID.Int64
+	}
+	data.Images = ih.publicImages(images, func(image database.Image) string {
+		return fmt.Sprintf("/s/%s/images/%d/raw", link.Token, image.ImageID)
+	})
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "shared album",
+		Data:    data,
+	})
+}
+
+// handleGetSharedRaw serves the file of a shared image , album links name the image in the url ,
+// the files are what a view loads so they don't count as views themselves
+func (ih *ImageHandler) handleGetSharedRaw(w http.ResponseWriter, r *http.Request) {
+	link, ok := ih.openShareLink(w, r, false)
+	if !ok {
+		return
+	}
+	var image database.Image
+	var err error
+	if link.ImageID.Valid {
+		image, err = ih.Store.GetImage(r.Context(), link.ImageID.Int64)
+	} else {
+		imageId, idErr := getImageId(r)
+		if idErr != nil {
+			respondWithError(w, http.StatusBadRequest, errors.New("album links need the id of the image"))
+			return
+		}
+		image, err = ih.Store.GetAlbumImage(r.Context(), database.GetAlbumImageParams{
+			AlbumID: link.AlbumID.Int64,
+			ImageID: int64(imageId),
+		})
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("image not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+		return
+	}
+	// the link can expire or run out of views at any time so the response must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	ih.renderImage(w, r, image)
+}
+
+// openShareLink checks the token , expiry , view limit and password of the link in the url and counts a view when countView is set ,
+// the password is sent in the X-Share-Password header
+func (ih *ImageHandler) openShareLink(w http.ResponseWriter, r *http.Request, countView bool) (database.ShareLink, bool) {
+	link, err := ih.Store.GetShareLinkByToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("share link not found"))
+			return database.ShareLink{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the share link"))
+		return database.ShareLink{}, false
+	}
+	if link.ExpiresAt.Valid && !link.ExpiresAt.Time.After(time.Now()) {
+		respondWithError(w, http.StatusGone, errShareLinkExpired)
+		return database.ShareLink{}, false
+	}
+	if link.MaxViews.Valid && link.ViewCount >= link.MaxViews.Int32 {
+		// the files of the last allowed view can still be loaded for a while
+		viewing := link.LastViewedAt.Valid && time.Since(link.LastViewedAt.Time) < shareViewWindow
+		if countView || !viewing {
+			respondWithError(w, http.StatusGone, errShareLinkExpired)
+			return database.ShareLink{}, false
+		}
+	}
+	if link.PasswordHash.Valid {
+		password := r.Header.Get("X-Share-Password")
+		if password == "" || auth.ComparePassword(password, link.PasswordHash.String) != nil {
+			respondWithError(w, http.StatusUnauthorized, errors.New("this share link needs a valid password"))
+			return database.ShareLink{}, false
+		}
+	}
+	if !countView {
+		return link, true
+	}
+	link, err = ih.Store.RecordShareLinkView(r.Context(), link.ShareID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusGone, errShareLinkExpired)
+			return database.ShareLink{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to open the share link"))
+		return database.ShareLink{}, false
+	}
+	return link, true
+}
+
+// getPublicImage loads the image in the url if it is public , private and unlisted images are reported as missing
+func (ih *ImageHandler) getPublicImage(w http.ResponseWriter, r *http.Request) (database.Image, bool) {
+	imageId, err := getImageId(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Image{}, false
+	}
+	image, err := ih.Store.GetImage(r.Context(), int64(imageId))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+		return database.Image{}, false
+	}
+	if err != nil || image.Visibility != visibilityPublic {
+		respondWithError(w, http.StatusNotFound, errors.New("image not found"))
+		return database.Image{}, false
+	}
+	return image, true
+}
+
+// getUnlistedImage loads the unlisted image with the token in the url
+func (ih *ImageHandler) getUnlistedImage(w http.ResponseWriter, r *http.Request) (database.Image, bool) {
+	image, err := ih.Store.GetUnlistedImage(r.Context(), sql.NullString{String: chi.URLParam(r, "token"), Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("image not found"))
+			return database.Image{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get image"))
+		return database.Image{}, false
+	}
+	return image, true
+}
+
+// renderImage writes the file of the image without its metadata , the width , height , format , rotate and flip query params transform it first ,
+// the number of images rendered at the same time and the size of the originals are bounded since anyone can call the public routes
+func (ih *ImageHandler) renderImage(w http.ResponseWriter, r *http.Request, image database.Image) {
+	request, err := getRenderTransformations(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if image.FileSize > maxRenderFileSize {
+		respondWithError(w, http.StatusUnprocessableEntity, errRenderTooLarge)
+		return
+	}
+	select {
+	case ih.renders <- struct{}{}:
+		defer func() { <-ih.renders }()
+	case <-r.Context().Done():
+		return
+	}
+	reader, err := ih.FileStorage.Download(r.Context(), image.FileName)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the image file"))
+		return
+	}
+	defer reader.Close()
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, io.LimitReader(reader, maxRenderFileSize+1)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the image file"))
+		return
+	}
+	if data.Len() > maxRenderFileSize {
+		respondWithError(w, http.StatusUnprocessableEntity, errRenderTooLarge)
+		return
+	}
+	output := data.Bytes()
+	if request != nil {
+		if output, err = imgproc.Transform(ih.ImageProcessor, output, request); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	// the original can carry the GPS location and other EXIF details in its metadata
+	if output, err = ih.ImageProcessor.StripMetadata(output); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to strip the image metadata"))
+		return
+	}
+	respondWithImage(w, output)
+}
+
+// getRenderTransformations builds the transformations of the public routes from the query params , it returns nil when there are none
+func getRenderTransformations(r *http.Request) (*models.TransformationsRequest, error) {
+	query := r.URL.Query()
+	request := &models.TransformationsRequest{}
+	transformed := false
+
+	width, height := 0, 0
+	for key, target := range map[string]*int{"width": &width, "height": &height} {
+		if value := query.Get(key); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 || parsed > maxRenderSize {
+				return nil, fmt.Errorf("%s must be between 1 and %d", key, maxRenderSize)
+			}
+			*target = parsed
+		}
+	}
+	if width > 0 || height > 0 {
+		request.Resize = &models.ResizeImageRequest{Width: width, Height: height}
+		transformed = true
+	}
+	if value := query.Get("rotate"); value != "" {
+		angle, err := strconv.Atoi(value)
+		if err != nil || angle%90 != 0 {
+			return nil, errors.New("rotate must be a multiple of 90")
+		}
+		request.Rotate = &models.RotateImageRequest{Angle: angle}
+		transformed = true
+	}
+	if query.Get("flip") == "true" {
+		flip := true
+		request.Flip = &flip
+		transformed = true
+	}
+	if format := query.Get("format"); format != "" {
+		if format != "jpeg" && format != "png" && format != "webp" {
+			return nil, errors.New("format must be jpeg , png or webp")
+		}
+		request.Convert = &models.ConvertImageRequest{ImageType: format}
+		transformed = true
+	}
+	if !transformed {
+		return nil, nil
+	}
+	return request, nil
+}
+
+// publicImage strips the details that only its owner should see , the GPS location is always removed ,
+// url is the raw route that serves the file without its metadata since the stored original still has it
+func (ih *ImageHandler) publicImage(image database.Image, url string) models.PublicImageResponse {
+	response := models.PublicImageResponse{
+		ImageID:   image.ImageID,
+		FileName:  image.FileName,
+		FileSize:  image.FileSize,
+		Url:       url,
+		CreatedAt: image.CreatedAt,
+	}
+	if image.Metadata.Valid {
+		response.Metadata = imgproc.RedactGPS(image.Metadata.RawMessage, imgproc.GPSRedact)
+	}
+	return response
+}
+
+func (ih *ImageHandler) publicImages(images []database.Image, rawURL func(database.Image) string) []models.PublicImageResponse {
+	data := make([]models.PublicImageResponse, 0, len(images))
+	for _, image := range images {
+		data = append(data, ih.publicImage(image, rawURL(image)))
+	}
+	return data
+}
+
+func publicImageRawURL(image database.Image) string {
+	return fmt.Sprintf("/public/images/%d/raw", image.ImageID)
+}
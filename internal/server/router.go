@@ -15,9 +15,9 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "HEAD", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Tus-Resumable", "Upload-Length", "Upload-Defer-Length", "Upload-Metadata", "Upload-Offset", "X-Share-Password"},
+		ExposedHeaders:   []string{"Link", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -26,14 +26,138 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Get("/", handleHomeRoute)
 	r.Post("/register", s.UserHandler.handleCreateUser)
 	r.Post("/login", s.UserHandler.handleLogin)
+	r.Post("/tokens/renew", s.UserHandler.handleRenewAccessToken)
+	r.Post("/logout", s.UserHandler.handleLogout)
+	r.Get("/verify-email", s.UserHandler.handleVerifyEmail)
+	r.With(AuthMiddleware(s.AuthMaker)).Post("/verify-email/resend", s.UserHandler.handleResendVerificationEmail)
+	r.Get("/.well-known/jwks.json", s.UserHandler.handleGetJWKS)
+
+	// unauthenticated routes for unlisted and public images and share links
+	r.Get("/public/images", s.ImageHandler.handleGetPublicImages)
+	r.Get("/public/images/{imageId}", s.ImageHandler.handleGetPublicImage)
+	r.Get("/public/images/{imageId}/raw", s.ImageHandler.handleGetPublicImageRaw)
+	r.Get("/u/{token}", s.ImageHandler.handleGetUnlistedImage)
+	r.Get("/u/{token}/raw", s.ImageHandler.handleGetUnlistedImageRaw)
+	r.Get("/s/{token}", s.ImageHandler.handleGetShared)
+	r.Get("/s/{token}/raw", s.ImageHandler.handleGetSharedRaw)
+	r.Get("/s/{token}/images/{imageId}/raw", s.ImageHandler.handleGetSharedRaw)
 
 	r.Route("/images", func(r chi.Router) {
 		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Use(TenantMiddleware(s.Store))
 		r.Get("/", s.ImageHandler.handleGetImages)
-		r.Post("/", s.ImageHandler.handleImageUpload)
+		// viewers can only read the library of the organization
+		r.With(RequireRole(roleMember), s.UserHandler.requireVerifiedEmail).Post("/", s.ImageHandler.handleImageUpload)
+		r.With(RequireRole(roleMember), s.UserHandler.requireVerifiedEmail).Post("/bulk", s.ImageHandler.handleBulkUpload)
+		r.With(RequireRole(roleMember), s.UserHandler.requireVerifiedEmail).Post("/import", s.ImageHandler.handleImportImage)
+		r.Get("/export", s.ImageHandler.handleExportImages)
+		r.With(RequireRole(roleMember), s.UserHandler.requireVerifiedEmail).Post("/presign", s.ImageHandler.handlePresignUpload)
+		r.With(RequireRole(roleMember), s.UserHandler.requireVerifiedEmail).Post("/presign/complete", s.ImageHandler.handleCompletePresignedUpload)
+		r.Route("/uploads", func(r chi.Router) {
+			r.Use(tusMiddleware)
+			r.Use(RequireRole(roleMember))
+			r.Use(s.UserHandler.requireVerifiedEmail)
+			r.Options("/", s.ImageHandler.handleUploadOptions)
+			r.Post("/", s.ImageHandler.handleCreateUpload)
+			r.Head("/{uploadId}", s.ImageHandler.handleUploadHead)
+			r.Get("/{uploadId}", s.ImageHandler.handleGetUpload)
+			r.Patch("/{uploadId}", s.ImageHandler.handleUploadPatch)
+			r.Delete("/{uploadId}", s.ImageHandler.handleUploadDelete)
+		})
+		r.With(RequireRole(roleMember)).Post("/batch/transform", s.ImageHandler.handleBatchTransformations)
+		r.With(RequireRole(roleMember)).Post("/tags/bulk", s.ImageHandler.handleBulkTag)
+		r.Get("/trash", s.ImageHandler.handleGetTrash)
+		r.Post("/trash/{imageId}/restore", s.ImageHandler.handleRestoreImage)
+		r.Delete("/trash/{imageId}", s.ImageHandler.handlePurgeImage)
 		r.Get("/{imageId}", s.ImageHandler.handleGetImage)
+		r.With(s.UserHandler.requireVerifiedEmail).Put("/{imageId}", s.ImageHandler.handleReplaceImage)
+		r.Get("/{imageId}/versions", s.ImageHandler.handleGetImageVersions)
+		r.Get("/{imageId}/versions/{version}", s.ImageHandler.handleGetImageVersion)
+		r.Post("/{imageId}/versions/{version}/revert", s.ImageHandler.handleRevertImage)
+		r.Get("/{imageId}/similar", s.ImageHandler.handleGetSimilarImages)
+		r.Get("/{imageId}/metadata", s.ImageHandler.handleGetImageMetadata)
+		r.Get("/{imageId}/placeholder", s.ImageHandler.handleGetImagePlaceholder)
+		r.Get("/{imageId}/tags", s.ImageHandler.handleGetImageTags)
+		r.Post("/{imageId}/tags", s.ImageHandler.handleAddImageTags)
+		r.Delete("/{imageId}/tags/{tag}", s.ImageHandler.handleRemoveImageTag)
+		r.Patch("/{imageId}/attributes", s.ImageHandler.handleUpdateImageAttributes)
+		r.Patch("/{imageId}/visibility", s.ImageHandler.handleSetImageVisibility)
+		r.Post("/{imageId}/shares", s.ImageHandler.handleCreateImageShare)
+		r.Get("/{imageId}/permissions", s.ImageHandler.handleGetImagePermissions)
+		r.Post("/{imageId}/permissions", s.ImageHandler.handleGrantImagePermission)
+		r.Delete("/{imageId}/permissions/{userId}", s.ImageHandler.handleRevokeImagePermission)
 		r.Post("/{imageId}/transform", s.ImageHandler.handleImageTransformations)
 		r.Delete("/{imageId}/delete", s.ImageHandler.handleDeleteImage)
+		r.With(RequireRole(roleMember)).Post("/{imageId}/jobs", s.JobHandler.handleCreateJob)
+	})
+
+	r.Route("/albums", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Use(TenantMiddleware(s.Store))
+		r.Get("/", s.ImageHandler.handleGetAlbums)
+		r.With(RequireRole(roleMember)).Post("/", s.ImageHandler.handleCreateAlbum)
+		r.Get("/{albumId}", s.ImageHandler.handleGetAlbum)
+		r.Patch("/{albumId}", s.ImageHandler.handleUpdateAlbum)
+		r.Delete("/{albumId}", s.ImageHandler.handleDeleteAlbum)
+		r.Post("/{albumId}/images", s.ImageHandler.handleAddAlbumImages)
+		r.Delete("/{albumId}/images", s.ImageHandler.handleRemoveAlbumImages)
+		r.Put("/{albumId}/order", s.ImageHandler.handleReorderAlbumImages)
+		r.Get("/{albumId}/download", s.ImageHandler.handleDownloadAlbum)
+		r.Post("/{albumId}/shares", s.ImageHandler.handleCreateAlbumShare)
+		r.Get("/{albumId}/permissions", s.ImageHandler.handleGetAlbumPermissions)
+		r.Post("/{albumId}/permissions", s.ImageHandler.handleGrantAlbumPermission)
+		r.Delete("/{albumId}/permissions/{userId}", s.ImageHandler.handleRevokeAlbumPermission)
+	})
+
+	r.Route("/shared-with-me", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.ImageHandler.handleGetSharedWithMe)
+	})
+
+	r.Route("/shares", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.ImageHandler.handleGetShares)
+		r.Delete("/{shareId}", s.ImageHandler.handleDeleteShare)
+	})
+
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Use(TenantMiddleware(s.Store))
+		r.Get("/", s.ImageHandler.handleGetTags)
+	})
+
+	r.Route("/orgs", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.OrgHandler.handleGetOrganizations)
+		r.Post("/", s.OrgHandler.handleCreateOrganization)
+		r.Patch("/{orgId}", s.OrgHandler.handleUpdateOrganization)
+		r.Delete("/{orgId}", s.OrgHandler.handleDeleteOrganization)
+		r.Post("/{orgId}/switch", s.OrgHandler.handleSwitchOrganization)
+		r.Get("/{orgId}/members", s.OrgHandler.handleGetMembers)
+		r.Post("/{orgId}/members", s.OrgHandler.handleAddMember)
+		r.Delete("/{orgId}/members/{userId}", s.OrgHandler.handleRemoveMember)
+	})
+
+	r.Route("/sessions", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.UserHandler.handleGetSessions)
+		r.Delete("/{sessionId}", s.UserHandler.handleRevokeSession)
+	})
+
+	r.Route("/jobs", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.JobHandler.handleGetJobs)
+		r.Get("/{jobId}", s.JobHandler.handleGetJob)
+		r.Get("/{jobId}/result", s.JobHandler.handleGetJobResult)
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(AuthMiddleware(s.AuthMaker))
+		r.Get("/", s.WebhookHandler.handleGetWebhooks)
+		r.Post("/", s.WebhookHandler.handleCreateWebhook)
+		r.Delete("/{webhookId}", s.WebhookHandler.handleDeleteWebhook)
+		r.Get("/{webhookId}/deliveries", s.WebhookHandler.handleGetWebhookDeliveries)
+		r.Post("/{webhookId}/deliveries/{deliveryId}/redeliver", s.WebhookHandler.handleRedeliver)
 	})
 
 	return r
@@ -6,9 +6,11 @@ import (
 
 	"github.com/mbeka02/image-service/internal/auth"
 	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
 	"github.com/mbeka02/image-service/internal/imgproc"
 	"github.com/mbeka02/image-service/internal/imgstore"
 	"github.com/mbeka02/image-service/internal/mailer"
+	"github.com/mbeka02/image-service/internal/webhook"
 )
 
 type Server struct {
@@ -20,20 +22,73 @@ type Server struct {
 	ImageProcessor      imgproc.ImageProcessor
 	ImageHandler        *ImageHandler
 	UserHandler         *UserHandler
+	JobHandler          *JobHandler
+	WebhookHandler      *WebhookHandler
+	OrgHandler          *OrgHandler
 	AccessTokenDuration time.Duration
 }
 
-func NewServer(addr string, store *database.Store, maker auth.Maker, duration time.Duration, mailer *mailer.Mailer, fileStorage imgstore.Storage, imageProcessor imgproc.ImageProcessor) *http.Server {
+// Config holds the dependencies and settings of the server , it is filled in from the app config in main
+type Config struct {
+	Addr                 string
+	Store                *database.Store
+	AuthMaker            auth.Maker
+	AccessTokenDuration  time.Duration
+	RefreshTokenDuration time.Duration
+	Mailer               *mailer.Mailer
+	FileStorage          imgstore.Storage
+	ImageProcessor       imgproc.ImageProcessor
+	Webhooks             *webhook.Dispatcher
+	Fetcher              *fetch.Fetcher
+	// PresignExpiry is how long presigned upload urls stay valid , DownloadExpiry is the same for the signed image urls
+	PresignExpiry  time.Duration
+	DownloadExpiry time.Duration
+	JobMaxAttempts int
+	GPSPolicy      string
+	TrashRetention time.Duration
+	MaxVersions    int
+	// AppURL is the base of the links in emails
+	AppURL               string
+	VerificationTTL      time.Duration
+	RequireVerifiedEmail bool
+}
+
+func NewServer(config Config) *http.Server {
+	imageHandler := &ImageHandler{
+		Store:          config.Store,
+		FileStorage:    config.FileStorage,
+		ImageProcessor: config.ImageProcessor,
+		Webhooks:       config.Webhooks,
+		Fetcher:        config.Fetcher,
+		PresignExpiry:  config.PresignExpiry,
+		DownloadExpiry: config.DownloadExpiry,
+		GPSPolicy:      config.GPSPolicy,
+		TrashRetention: config.TrashRetention,
+		MaxVersions:    config.MaxVersions,
+		renders:        make(chan struct{}, maxConcurrentRenders),
+	}
 	srv := Server{
-		Addr:                addr,
-		Store:               store,
-		AuthMaker:           maker,
-		AccessTokenDuration: duration,
-		Mailer:              mailer,
-		FileStorage:         fileStorage,
-		ImageProcessor:      imageProcessor,
-		ImageHandler:        &ImageHandler{Store: store, FileStorage: fileStorage, ImageProcessor: imageProcessor},
-		UserHandler:         &UserHandler{Store: store, AuthMaker: maker, Mailer: mailer, AccessTokenDuration: duration},
+		Addr:                config.Addr,
+		Store:               config.Store,
+		AuthMaker:           config.AuthMaker,
+		AccessTokenDuration: config.AccessTokenDuration,
+		Mailer:              config.Mailer,
+		FileStorage:         config.FileStorage,
+		ImageProcessor:      config.ImageProcessor,
+		ImageHandler:        imageHandler,
+		UserHandler: &UserHandler{
+			Store:                config.Store,
+			AuthMaker:            config.AuthMaker,
+			Mailer:               config.Mailer,
+			AccessTokenDuration:  config.AccessTokenDuration,
+			RefreshTokenDuration: config.RefreshTokenDuration,
+			AppURL:               config.AppURL,
+			VerificationTTL:      config.VerificationTTL,
+			RequireVerifiedEmail: config.RequireVerifiedEmail,
+		},
+		JobHandler:     &JobHandler{Store: config.Store, MaxAttempts: int32(config.JobMaxAttempts), Images: imageHandler},
+		WebhookHandler: &WebhookHandler{Store: config.Store},
+		OrgHandler:     &OrgHandler{Store: config.Store, AuthMaker: config.AuthMaker, AccessTokenDuration: config.AccessTokenDuration},
 	}
 
 	return &http.Server{
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("the refresh token is invalid")
+	ErrSessionBlocked      = errors.New("the session has been revoked")
+	ErrSessionExpired      = errors.New("the session has expired")
+	// a refresh token is only valid until it is rotated , seeing an old one means it leaked so the whole session is revoked
+	ErrRefreshTokenReused = errors.New("the refresh token was already used , the session has been revoked")
+)
+
+// startSession creates a session for the user and returns the tokens for it
+func (uh *UserHandler) startSession(r *http.Request, user database.User, orgId int64) (models.AuthResponse, error) {
+	sessionId, err := newSessionId()
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+	refreshToken, refreshTokenHash, err := auth.NewRefreshToken(sessionId)
+	if err != nil {
+		return models.AuthResponse{}, err
+	}
+	session, err := uh.Store.CreateSession(r.Context(), database.CreateSessionParams{
+		SessionID:        sessionId,
+		UserID:           user.UserID,
+		OrgID:            orgId,
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        r.UserAgent(),
+		ClientIp:         clientIp(r),
+		ExpiresAt:        time.Now().Add(uh.RefreshTokenDuration),
+	})
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("unable to create the session:%v", err)
+	}
+	accessToken, err := uh.AuthMaker.Create(user.Email, user.UserID, orgId, uh.AccessTokenDuration)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("unable to create the access token:%v", err)
+	}
+	return models.AuthResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  time.Now().Add(uh.AccessTokenDuration),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
+		User:                  models.NewUserResponse(user),
+	}, nil
+}
+
+// handleRenewAccessToken rotates the refresh token of the session and issues a new access token ,
+// the session keeps its organization unless the request moves it to another one
+func (uh *UserHandler) handleRenewAccessToken(w http.ResponseWriter, r *http.Request) {
+	request := models.RenewAccessTokenRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	session, tokenHash, ok := uh.getRefreshSession(w, r, request.RefreshToken)
+	if !ok {
+		return
+	}
+	user, err := uh.Store.GetUser(r.Context(), session.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the user"))
+		return
+	}
+	orgId, err := uh.sessionOrganization(r, session, request.OrgID)
+	if err != nil {
+		if errors.Is(err, ErrNotAMember) {
+			respondWithError(w, http.StatusUnauthorized, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	refreshToken, refreshTokenHash, err := auth.NewRefreshToken(session.SessionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rotated, err := uh.Store.RotateSession(r.Context(), database.RotateSessionParams{
+		NewTokenHash: refreshTokenHash,
+		OrgID:        orgId,
+		SessionID:    session.SessionID,
+		OldTokenHash: tokenHash,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// another renewal used the token first
+			uh.blockSession(w, r, session.SessionID)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to renew the session"))
+		return
+	}
+	accessToken, err := uh.AuthMaker.Create(user.Email, user.UserID, orgId, uh.AccessTokenDuration)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the access token"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, models.RenewAccessTokenResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  time.Now().Add(uh.AccessTokenDuration),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: rotated.ExpiresAt,
+	})
+}
+
+// handleLogout revokes the session of the refresh token , access tokens that were already issued stay valid until they expire
+func (uh *UserHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	request := models.LogoutRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	session, _, ok := uh.getRefreshSession(w, r, request.RefreshToken)
+	if !ok {
+		return
+	}
+	if err := uh.Store.BlockSession(r.Context(), session.SessionID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to revoke the session"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "logged out",
+	})
+}
+
+func (uh *UserHandler) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sessions, err := uh.Store.GetActiveSessions(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the sessions"))
+		return
+	}
+	data := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		data[i] = models.NewSessionResponse(session)
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "sessions",
+		Data:    data,
+	})
+}
+
+// handleRevokeSession signs the user out of one of their sessions
+func (uh *UserHandler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	revoked, err := uh.Store.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		SessionID: chi.URLParam(r, "sessionId"),
+		UserID:    payload.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to revoke the session"))
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "session revoked",
+	})
+}
+
+// getRefreshSession loads the session of the refresh token and returns it with the hash of the token ,
+// it writes the error response and returns false when the token can't be used
+func (uh *UserHandler) getRefreshSession(w http.ResponseWriter, r *http.Request, refreshToken string) (database.Session, string, bool) {
+	sessionId, tokenHash, err := auth.ParseRefreshToken(refreshToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, ErrInvalidRefreshToken)
+		return database.Session{}, "", false
+	}
+	session, err := uh.Store.GetSession(r.Context(), sessionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, ErrInvalidRefreshToken)
+			return database.Session{}, "", false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the session"))
+		return database.Session{}, "", false
+	}
+	if session.IsBlocked {
+		respondWithError(w, http.StatusUnauthorized, ErrSessionBlocked)
+		return database.Session{}, "", false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		respondWithError(w, http.StatusUnauthorized, ErrSessionExpired)
+		return database.Session{}, "", false
+	}
+	if !auth.SameRefreshHash(tokenHash, session.RefreshTokenHash) {
+		uh.blockSession(w, r, session.SessionID)
+		return database.Session{}, "", false
+	}
+	return session, tokenHash, true
+}
+
+// blockSession revokes a session whose refresh token was reused
+func (uh *UserHandler) blockSession(w http.ResponseWriter, r *http.Request, sessionId string) {
+	if err := uh.Store.BlockSession(r.Context(), sessionId); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to revoke the session"))
+		return
+	}
+	respondWithError(w, http.StatusUnauthorized, ErrRefreshTokenReused)
+}
+
+// sessionOrganization returns the organization the renewed access token is scoped to ,
+// a session whose user was removed from its organization falls back to the personal one
+func (uh *UserHandler) sessionOrganization(r *http.Request, session database.Session, requested int64) (int64, error) {
+	orgId := session.OrgID
+	if requested != 0 {
+		orgId = requested
+	}
+	_, err := uh.Store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: orgId, UserID: session.UserID})
+	if err == nil {
+		return orgId, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("unable to get the membership")
+	}
+	if requested != 0 {
+		return 0, ErrNotAMember
+	}
+	org, err := uh.Store.GetPersonalOrganization(r.Context(), sql.NullInt64{Int64: session.UserID, Valid: true})
+	if err != nil {
+		return 0, errors.New("unable to get the personal organization")
+	}
+	return org.OrgID, nil
+}
+
+func newSessionId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("unable to generate a session id:%v", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// clientIp is the address the request came from , without the port
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+const (
+	visibilityPrivate  = "private"
+	visibilityUnlisted = "unlisted"
+	visibilityPublic   = "public"
+)
+
+func (ih *ImageHandler) handleSetImageVisibility(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	request := models.SetVisibilityRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	// only used when the image becomes unlisted , an image that already is keeps its token
+	token, err := newShareToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	updatedImage, err := ih.Store.SetImageVisibility(r.Context(), database.SetImageVisibilityParams{
+		ImageID:       image.ImageID,
+		Visibility:    request.Visibility,
+		UnlistedToken: sql.NullString{String: token, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to update the visibility"))
+		return
+	}
+	if err := ih.signImage(r.Context(), &updatedImage); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "visibility updated",
+		Data:    updatedImage,
+	})
+}
+
+func (ih *ImageHandler) handleCreateImageShare(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	ih.createShareLink(w, r, database.CreateShareLinkParams{
+		ImageID: sql.NullInt64{Int64: image.ImageID, Valid: true},
+	})
+}
+
+func (ih *ImageHandler) handleCreateAlbumShare(w http.ResponseWriter, r *http.Request) {
+	album, ok := ih.getAuthorizedAlbum(w, r, accessOwner)
+	if !ok {
+		return
+	}
+	ih.createShareLink(w, r, database.CreateShareLinkParams{
+		AlbumID: sql.NullInt64{Int64: album.AlbumID, Valid: true},
+	})
+}
+
+// createShareLink completes params with the user creating the link , a new token and the limits in the request
+func (ih *ImageHandler) createShareLink(w http.ResponseWriter, r *http.Request, params database.CreateShareLinkParams) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	params.UserID = payload.UserID
+	request := models.CreateShareLinkRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	token, err := newShareToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	params.Token = token
+	if request.Password != "" {
+		hash, err := auth.HashPassword(request.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		params.PasswordHash = sql.NullString{String: hash, Valid: true}
+	}
+	if request.ExpiresIn > 0 {
+		params.ExpiresAt = sql.NullTime{Time: time.Now().Add(time.Duration(request.ExpiresIn) * time.Second), Valid: true}
+	}
+	if request.MaxViews > 0 {
+		params.MaxViews = sql.NullInt32{Int32: request.MaxViews, Valid: true}
+	}
+	link, err := ih.Store.CreateShareLink(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the share link"))
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, APIResponse{
+		Status:  http.StatusCreated,
+		Message: "share link created",
+		Data:    models.NewShareLinkResponse(link),
+	})
+}
+
+func (ih *ImageHandler) handleGetShares(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	links, err := ih.Store.GetUserShareLinks(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the share links"))
+		return
+	}
+	data := make([]models.ShareLinkResponse, 0, len(links))
+	for _, link := range links {
+		data = append(data, models.NewShareLinkResponse(link))
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "share links",
+		Data:    data,
+	})
+}
+
+func (ih *ImageHandler) handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	shareId, err := strconv.ParseInt(chi.URLParam(r, "shareId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	deleted, err := ih.Store.DeleteShareLink(r.Context(), database.DeleteShareLinkParams{
+		ShareID: shareId,
+		UserID:  payload.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the share link"))
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("share link not found"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "share link deleted",
+	})
+}
+
+func newShareToken() (string, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("unable to generate a share token:%v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
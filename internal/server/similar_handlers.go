@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mbeka02/image-service/internal/database"
+)
+
+const (
+	defaultSimilarDistance = 10
+	defaultSimilarLimit    = 20
+	maxSimilarLimit        = 100
+)
+
+// handleGetSimilarImages returns the images of the organization whose perceptual hash is within the distance query param of the image's hash
+func (ih *ImageHandler) handleGetSimilarImages(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	distance, err := strconv.Atoi(r.URL.Query().Get("distance"))
+	if err != nil {
+		distance = defaultSimilarDistance
+	}
+	if distance < 0 || distance > 64 {
+		respondWithError(w, http.StatusBadRequest, errors.New("the distance must be between 0 and 64"))
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSimilarLimit
+	}
+	if limit > maxSimilarLimit {
+		limit = maxSimilarLimit
+	}
+
+	if !image.Phash.Valid {
+		respondWithError(w, http.StatusConflict, errors.New("the image does not have a perceptual hash yet"))
+		return
+	}
+	similarImages, err := ih.Store.GetSimilarImages(r.Context(), database.GetSimilarImagesParams{
+		Phash:       image.Phash.Int64,
+		OrgID:       image.OrgID,
+		ImageID:     image.ImageID,
+		MaxDistance: int32(distance),
+		MaxResults:  int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get similar images"))
+		return
+	}
+	for i := range similarImages {
+		url, err := ih.FileStorage.SignedURL(r.Context(), similarImages[i].FileName, ih.DownloadExpiry)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		similarImages[i].StorageUrl = url
+		similarImages[i].Metadata = ih.redactMetadata(similarImages[i].Metadata)
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "similar images",
+		Data:    similarImages,
+	})
+}
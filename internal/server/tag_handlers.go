@@ -0,0 +1,218 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+// normalizeTag makes tags case insensitive , "Beach " and "beach" are the same tag
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func (ih *ImageHandler) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	tags, err := ih.Store.GetOrgTags(r.Context(), tenant.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get tags"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "tags",
+		Data:    tags,
+	})
+}
+
+func (ih *ImageHandler) handleGetImageTags(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	ih.respondWithImageTags(w, r, image.ImageID)
+}
+
+func (ih *ImageHandler) handleAddImageTags(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.TagImageRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, name := range request.Tags {
+		if _, err := ih.addTag(r, image.UserID, image.OrgID, name, []int64{image.ImageID}); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	ih.respondWithImageTags(w, r, image.ImageID)
+}
+
+func (ih *ImageHandler) handleRemoveImageTag(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	removed, err := ih.removeTag(r, image.OrgID, chi.URLParam(r, "tag"), []int64{image.ImageID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("the image does not have this tag"))
+		return
+	}
+	ih.respondWithImageTags(w, r, image.ImageID)
+}
+
+func (ih *ImageHandler) handleBulkTag(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.BulkTagRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.Add) == 0 && len(request.Remove) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("there are no tags to add or remove"))
+		return
+	}
+	response := models.BulkTagResponse{}
+	for _, name := range request.Add {
+		added, err := ih.addTag(r, tenant.UserID, tenant.OrgID, name, request.ImageIDs)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response.Added += added
+	}
+	for _, name := range request.Remove {
+		removed, err := ih.removeTag(r, tenant.OrgID, name, request.ImageIDs)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response.Removed += removed
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "tags updated",
+		Data:    response,
+	})
+}
+
+// addTag creates the tag if the organization doesn't have it yet and adds it to the images , userId is recorded as the creator of a new tag
+func (ih *ImageHandler) addTag(r *http.Request, userId, orgId int64, name string, imageIds []int64) (int64, error) {
+	name = normalizeTag(name)
+	if name == "" {
+		return 0, nil
+	}
+	tag, err := ih.Store.UpsertTag(r.Context(), database.UpsertTagParams{UserID: userId, OrgID: orgId, Name: name})
+	if err != nil {
+		return 0, errors.New("unable to save the tag")
+	}
+	added, err := ih.Store.AddTagToImages(r.Context(), database.AddTagToImagesParams{
+		TagID:    tag.TagID,
+		OrgID:    orgId,
+		ImageIds: imageIds,
+	})
+	if err != nil {
+		return 0, errors.New("unable to tag the images")
+	}
+	return added, nil
+}
+
+// removeTag removes the tag from the images , a tag the organization doesn't have removes nothing
+func (ih *ImageHandler) removeTag(r *http.Request, orgId int64, name string, imageIds []int64) (int64, error) {
+	tag, err := ih.Store.GetTagByName(r.Context(), database.GetTagByNameParams{OrgID: orgId, Name: normalizeTag(name)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, errors.New("unable to get the tag")
+	}
+	removed, err := ih.Store.RemoveTagFromImages(r.Context(), database.RemoveTagFromImagesParams{
+		TagID:    tag.TagID,
+		ImageIds: imageIds,
+	})
+	if err != nil {
+		return 0, errors.New("unable to untag the images")
+	}
+	return removed, nil
+}
+
+func (ih *ImageHandler) respondWithImageTags(w http.ResponseWriter, r *http.Request, imageId int64) {
+	tags, err := ih.Store.GetImageTags(r.Context(), imageId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the image tags"))
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "image tags",
+		Data:    tags,
+	})
+}
+
+func (ih *ImageHandler) handleUpdateImageAttributes(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	request := models.UpdateAttributesRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	set := request.Set
+	if set == nil {
+		set = map[string]string{}
+	}
+	setAttributes, err := json.Marshal(set)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	remove := request.Remove
+	if remove == nil {
+		remove = []string{}
+	}
+	updatedImage, err := ih.Store.UpdateImageAttributes(r.Context(), database.UpdateImageAttributesParams{
+		RemoveKeys:    remove,
+		SetAttributes: setAttributes,
+		ImageID:       image.ImageID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to update the attributes"))
+		return
+	}
+	if err := ih.signImage(r.Context(), &updatedImage); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "attributes updated",
+		Data:    updatedImage,
+	})
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/trash"
+)
+
+const (
+	defaultTrashLimit = 50
+	maxTrashLimit     = 200
+)
+
+// handleGetTrash lists the deleted images of the organization , most recently deleted first
+func (ih *ImageHandler) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultTrashLimit
+	}
+	limit = min(limit, maxTrashLimit)
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	images, err := ih.Store.GetTrashedImages(r.Context(), database.GetTrashedImagesParams{
+		OrgID:  tenant.OrgID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the trash"))
+		return
+	}
+	if err := ih.signImages(r.Context(), images); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	trashed := make([]models.TrashedImage, len(images))
+	for i, image := range images {
+		trashed[i] = models.TrashedImage{
+			Image:   image,
+			PurgeAt: image.DeletedAt.Time.Add(ih.TrashRetention),
+		}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "trash",
+		Data:    trashed,
+	})
+}
+
+// handleRestoreImage moves an image out of the trash , anyone who could delete it can restore it
+func (ih *ImageHandler) handleRestoreImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getTrashedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	image, err := ih.Store.RestoreImage(r.Context(), image.ImageID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to restore the image"))
+		return
+	}
+	if err := ih.signImage(r.Context(), &image); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "restored the image",
+		Data:    image,
+	})
+}
+
+// handlePurgeImage permanently deletes an image in the trash before the retention period runs out
+func (ih *ImageHandler) handlePurgeImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getTrashedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	if !ih.canPurge(w, r, image) {
+		return
+	}
+	if err := trash.Purge(r.Context(), ih.Store, ih.FileStorage, image); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the image"))
+		return
+	}
+	// the image.deleted event was already published when it was moved to the trash
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "permanently deleted the image",
+		Data:    nil,
+	})
+}
+
+// canPurge checks that the user is an admin or owner of the organization the image belongs to , it writes the error response when they aren't
+func (ih *ImageHandler) canPurge(w http.ResponseWriter, r *http.Request, image database.Image) bool {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	member, err := ih.Store.GetOrgMember(r.Context(), database.GetOrgMemberParams{OrgID: image.OrgID, UserID: payload.UserID})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the membership"))
+		return false
+	}
+	if roleRanks[member.Role] < roleRanks[roleAdmin] {
+		respondWithError(w, http.StatusForbidden, errors.New("only admins can permanently delete images"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,401 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/trash"
+	"github.com/mbeka02/image-service/internal/uploads"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	// tusContentType is the only content type accepted by PATCH requests
+	tusContentType         = "application/offset+octet-stream"
+	maxResumableUploadSize = 2 << 30
+	// tusPartSize is how much of a PATCH body is buffered before it is stored as a chunk
+	tusPartSize = 8 << 20
+	// uploadFinishTimeout is how long a request that is finishing an upload keeps its claim ,
+	// another request can finish the upload after that
+	uploadFinishTimeout = 10 * time.Minute
+)
+
+// tusMiddleware sets the Tus-Resumable header on every response and rejects requests for other versions of the protocol
+func tusMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		// OPTIONS is used for discovery and GET is not part of the protocol
+		if r.Method != http.MethodOptions && r.Method != http.MethodGet && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			respondWithError(w, http.StatusPreconditionFailed, errors.New("unsupported tus version"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ih *ImageHandler) handleUploadOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.Itoa(maxResumableUploadSize))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateUpload implements the creation extension , the file name is read from the filename key of Upload-Metadata
+func (ih *ImageHandler) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	tenant, err := getTenant(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		respondWithError(w, http.StatusBadRequest, errors.New("deferred upload lengths are not supported"))
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid Upload-Length header"))
+		return
+	}
+	if length > maxResumableUploadSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("uploads can be at most %d bytes", maxResumableUploadSize))
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = "upload"
+	}
+	uploadId, err := newUploadId()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	upload, err := ih.Store.CreateUpload(r.Context(), database.CreateUploadParams{
+		UploadID:     uploadId,
+		UserID:       tenant.UserID,
+		OrgID:        tenant.OrgID,
+		FileName:     fileName,
+		UploadLength: length,
+		ExpiresAt:    time.Now().Add(uploads.Expiration),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the upload"))
+		return
+	}
+	w.Header().Set("Location", "/images/uploads/"+upload.UploadID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleUploadHead reports how much of the upload the server has received
+func (ih *ImageHandler) handleUploadHead(w http.ResponseWriter, r *http.Request) {
+	upload, ok := ih.getUserUpload(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.UploadLength, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetUpload returns the upload as JSON , the image id is set once the upload has been finalized
+func (ih *ImageHandler) handleGetUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := ih.getUserUpload(w, r)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "upload",
+		Data:    upload,
+	})
+}
+
+// handleUploadPatch stores the body as the next chunk of the upload , the upload is turned into an image once the last byte arrives
+func (ih *ImageHandler) handleUploadPatch(w http.ResponseWriter, r *http.Request) {
+	upload, ok := ih.getUserUpload(w, r)
+	if !ok {
+		return
+	}
+	if r.Header.Get("Content-Type") != tusContentType {
+		respondWithError(w, http.StatusUnsupportedMediaType, fmt.Errorf("the content type must be %s", tusContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid Upload-Offset header"))
+		return
+	}
+	if offset != upload.UploadOffset {
+		respondWithError(w, http.StatusConflict, fmt.Errorf("the upload is at offset %d", upload.UploadOffset))
+		return
+	}
+
+	if remaining := upload.UploadLength - upload.UploadOffset; remaining > 0 && r.ContentLength != 0 {
+		body := http.MaxBytesReader(w, r.Body, remaining)
+		// the body is stored in parts so that a connection that drops mid-request keeps what already arrived ,
+		// the parts are written without the request context for the same reason
+		ctx := context.WithoutCancel(r.Context())
+		part := make([]byte, min(remaining, tusPartSize))
+		for upload.UploadOffset < upload.UploadLength {
+			n, readErr := io.ReadFull(body, part[:min(int64(len(part)), upload.UploadLength-upload.UploadOffset)])
+			if n > 0 {
+				// the format is checked once the start of the file arrives instead of after the whole upload
+				if upload.UploadOffset == 0 && int64(n) >= min(sniffLen, upload.UploadLength) {
+					if err := validateImageHeader(part[:n]); err != nil {
+						if err := uploads.Delete(ctx, ih.Store, ih.FileStorage, upload); err != nil {
+							respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the upload"))
+							return
+						}
+						respondWithError(w, http.StatusBadRequest, err)
+						return
+					}
+				}
+				var status int
+				upload, status, err = ih.appendUploadPart(ctx, upload, part[:n])
+				if err != nil {
+					respondWithError(w, status, err)
+					return
+				}
+			}
+			if readErr == nil {
+				continue
+			}
+			if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+				break
+			}
+			// the client resumes from the offset HEAD returns
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("unable to read the body:%v", readErr))
+			return
+		}
+		// the whole upload arrived , anything after it doesn't belong to the upload
+		if upload.UploadOffset == upload.UploadLength {
+			var maxBytesError *http.MaxBytesError
+			if _, err := body.Read(make([]byte, 1)); errors.As(err, &maxBytesError) {
+				respondWithError(w, http.StatusRequestEntityTooLarge, errors.New("the chunk is larger than the rest of the upload"))
+				return
+			}
+		}
+	}
+
+	// finishing is retried by an empty PATCH if it failed the first time
+	if upload.UploadOffset == upload.UploadLength && !upload.ImageID.Valid {
+		if status, err := ih.finishUpload(r.Context(), upload); err != nil {
+			respondWithError(w, status, err)
+			return
+		}
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// appendUploadPart stores data as the next chunk of the upload , it returns the status to respond with when it fails
+func (ih *ImageHandler) appendUploadPart(ctx context.Context, upload database.Upload, data []byte) (database.Upload, int, error) {
+	chunkName := fmt.Sprintf("uploads/%s/%d", upload.UploadID, upload.UploadOffset)
+	chunk, err := ih.FileStorage.UploadStream(ctx, chunkName, "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		return upload, http.StatusInternalServerError, err
+	}
+	updated, err := ih.Store.AppendUploadChunk(ctx, database.AppendUploadChunkParams{
+		UploadID:     upload.UploadID,
+		UploadOffset: upload.UploadOffset,
+		ChunkSize:    chunk.Size,
+		Chunk:        chunk.FileName,
+	})
+	if err != nil {
+		ih.FileStorage.Delete(ctx, chunk.FileName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return upload, http.StatusConflict, errors.New("the upload was modified by another request")
+		}
+		return upload, http.StatusInternalServerError, errors.New("unable to save the chunk")
+	}
+	return updated, http.StatusOK, nil
+}
+
+// handleUploadDelete implements the termination extension
+func (ih *ImageHandler) handleUploadDelete(w http.ResponseWriter, r *http.Request) {
+	upload, ok := ih.getUserUpload(w, r)
+	if !ok {
+		return
+	}
+	if err := uploads.Delete(r.Context(), ih.Store, ih.FileStorage, upload); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the upload"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finishUpload claims the upload and turns it into an image , it returns the status to respond with when it fails
+func (ih *ImageHandler) finishUpload(ctx context.Context, upload database.Upload) (int, error) {
+	claimed, err := ih.Store.ClaimUpload(ctx, database.ClaimUploadParams{
+		UploadID:    upload.UploadID,
+		FinishingAt: sql.NullTime{Time: time.Now().Add(-uploadFinishTimeout), Valid: true},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("unable to claim the upload")
+	}
+	if claimed == 0 {
+		return http.StatusConflict, errors.New("the upload is being finished by another request")
+	}
+	status, err := ih.finishClaimedUpload(ctx, upload)
+	if err != nil {
+		// an empty PATCH can retry right away instead of waiting for the claim to expire
+		ih.Store.ReleaseUpload(context.WithoutCancel(ctx), upload.UploadID)
+	}
+	return status, err
+}
+
+// finishClaimedUpload joins the chunks , validates the result and stores it as an image
+func (ih *ImageHandler) finishClaimedUpload(ctx context.Context, upload database.Upload) (int, error) {
+	file, err := ih.assembleUpload(ctx, upload)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	metadata, err := validateImage(file)
+	if err != nil {
+		// the client can't fix the content of a finished upload so it is discarded
+		if err := uploads.Delete(ctx, ih.Store, ih.FileStorage, upload); err != nil {
+			return http.StatusInternalServerError, errors.New("unable to delete the upload")
+		}
+		return http.StatusBadRequest, err
+	}
+	createdImage, err := ih.storeImage(ctx, upload.UserID, upload.OrgID, upload.FileName, metadata, file, duplicatesAllow)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	completed, err := ih.Store.CompleteUpload(ctx, database.CompleteUploadParams{
+		UploadID: upload.UploadID,
+		ImageID:  sql.NullInt64{Int64: createdImage.ImageID, Valid: true},
+	})
+	if err != nil || completed == 0 {
+		// the upload points at another image or none at all , the image created here would be left behind
+		if err := trash.Purge(context.WithoutCancel(ctx), ih.Store, ih.FileStorage, createdImage); err != nil {
+			log.Printf("unable to remove image %d of upload %s:%v", createdImage.ImageID, upload.UploadID, err)
+		}
+		if err != nil {
+			return http.StatusInternalServerError, errors.New("unable to complete the upload")
+		}
+		return http.StatusConflict, errors.New("the upload was finished by another request")
+	}
+	uploads.DeleteChunks(ctx, ih.FileStorage, upload)
+	return http.StatusOK, nil
+}
+
+// assembleUpload copies the chunks into a temporary file , the caller is responsible for closing and removing it
+func (ih *ImageHandler) assembleUpload(ctx context.Context, upload database.Upload) (*os.File, error) {
+	file, err := os.CreateTemp("", "upload_*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a temporary file:%v", err)
+	}
+	for _, chunk := range upload.Chunks {
+		if err := ih.copyChunk(ctx, file, chunk); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("unable to read the temporary file:%v", err)
+	}
+	return file, nil
+}
+
+func (ih *ImageHandler) copyChunk(ctx context.Context, dst io.Writer, chunk string) error {
+	reader, err := ih.FileStorage.Download(ctx, chunk)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, err := io.Copy(dst, reader); err != nil {
+		return fmt.Errorf("unable to copy the chunk:%v", err)
+	}
+	return nil
+}
+
+func (ih *ImageHandler) getUserUpload(w http.ResponseWriter, r *http.Request) (database.Upload, bool) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Upload{}, false
+	}
+	upload, err := ih.Store.GetUpload(r.Context(), chi.URLParam(r, "uploadId"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("upload not found"))
+			return database.Upload{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the upload"))
+		return database.Upload{}, false
+	}
+	if upload.UserID != payload.UserID {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return database.Upload{}, false
+	}
+	if !upload.ImageID.Valid && time.Now().After(upload.ExpiresAt) {
+		respondWithError(w, http.StatusGone, errors.New("the upload has expired"))
+		return database.Upload{}, false
+	}
+	return upload, true
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header , a comma separated list of keys each followed by an optional base64 value
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if header == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid Upload-Metadata value for %s", fields[0])
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, errors.New("invalid Upload-Metadata header")
+		}
+	}
+	return metadata, nil
+}
+
+func newUploadId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("unable to generate an upload id:%v", err)
+	}
+	return hex.EncodeToString(id), nil
+}
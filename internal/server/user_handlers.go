@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -18,6 +21,14 @@ type UserHandler struct {
 	AuthMaker           auth.Maker
 	Mailer              *mailer.Mailer
 	AccessTokenDuration time.Duration
+	// RefreshTokenDuration is how long a session lasts without being renewed
+	RefreshTokenDuration time.Duration
+	// AppURL is where verification links point to , it is required since the host of the request can be forged
+	AppURL string
+	// VerificationTTL is how long an email verification link stays valid
+	VerificationTTL time.Duration
+	// RequireVerifiedEmail blocks uploads for users who haven't verified their email
+	RequireVerifiedEmail bool
 }
 
 func (uh *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -33,45 +44,43 @@ func (uh *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := uh.Store.CreateUser(r.Context(), database.CreateUserParams{
-		FullName: request.Fullname,
-		Email:    request.Email,
-		Password: passwordHash,
+	// the user can't exist without their personal organization
+	var user database.User
+	var org database.Organization
+	err = uh.Store.ExecTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		user, err = q.CreateUser(r.Context(), database.CreateUserParams{
+			FullName: request.Fullname,
+			Email:    request.Email,
+			Password: passwordHash,
+		})
+		if err != nil {
+			return err
+		}
+		org, err = createPersonalOrganization(r.Context(), q, user)
+		return err
 	})
 	if err != nil {
-
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				respondWithJSON(w, http.StatusForbidden, APIError{
-					Status:  http.StatusForbidden,
-					Message: "forbidden : the username or email are already in use",
-					Detail:  err.Error(),
-				})
-
-				return
-			}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			respondWithJSON(w, http.StatusForbidden, APIError{
+				Status:  http.StatusForbidden,
+				Message: "forbidden : the username or email are already in use",
+				Detail:  err.Error(),
+			})
+			return
 		}
 		respondWithError(w, http.StatusInternalServerError, errors.New("failed to create user"))
 		return
 	}
-	// response := APIResponse{
-	// 	Status:  http.StatusCreated,
-	// 	Message: "User created successfully",
-	// 	Data:    user,
-	// }
-	go func() {
-		if err := uh.Mailer.SendEmail(); err != nil {
-			fmt.Println("unable to send the email:", err)
-
-			return
-		}
-	}()
-	userResponse := models.NewUserResponse(user)
-	token, err := uh.AuthMaker.Create(user.Email, user.UserID, uh.AccessTokenDuration)
-	response := models.AuthResponse{
-		AccessToken: token,
-		User:        userResponse,
+	// the account works without the email , the user can ask for another one with POST /verify-email/resend
+	if err := uh.sendVerificationEmail(r, user); err != nil {
+		log.Printf("unable to send the verification email to user %d:%v", user.UserID, err)
+	}
+	response, err := uh.startSession(r, user, org.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
 	}
 
 	if err := respondWithJSON(w, http.StatusCreated, response); err != nil {
@@ -97,14 +106,38 @@ func (uh *UserHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, err)
 		return
 	}
-	userResponse := models.NewUserResponse(user)
-	token, err := uh.AuthMaker.Create(user.Email, user.UserID, uh.AccessTokenDuration)
-	response := models.AuthResponse{
-		AccessToken: token,
-		User:        userResponse,
+	// every session starts in the personal organization , POST /orgs/{orgId}/switch moves to another one
+	org, err := uh.Store.GetPersonalOrganization(r.Context(), sql.NullInt64{Int64: user.UserID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the personal organization"))
+		return
+	}
+	response, err := uh.startSession(r, user, org.OrgID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
 	}
 	if err := respondWithJSON(w, http.StatusOK, response); err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
 }
+
+// createPersonalOrganization creates the organization that holds the library of a new user , q is the transaction that created the user
+func createPersonalOrganization(ctx context.Context, q *database.Queries, user database.User) (database.Organization, error) {
+	org, err := q.CreateOrganization(ctx, database.CreateOrganizationParams{
+		Name:           user.FullName,
+		PersonalUserID: sql.NullInt64{Int64: user.UserID, Valid: true},
+	})
+	if err != nil {
+		return database.Organization{}, fmt.Errorf("unable to create the personal organization:%v", err)
+	}
+	if _, err := q.AddOrgMember(ctx, database.AddOrgMemberParams{
+		OrgID:  org.OrgID,
+		UserID: user.UserID,
+		Role:   roleOwner,
+	}); err != nil {
+		return database.Organization{}, fmt.Errorf("unable to add the user to the personal organization:%v", err)
+	}
+	return org, nil
+}
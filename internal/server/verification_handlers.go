@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/auth"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/models"
+)
+
+const (
+	// verificationCooldown is how long a user waits between verification emails
+	verificationCooldown = time.Minute
+	// verificationDailyLimit caps the verification emails a user gets in a day
+	verificationDailyLimit = 5
+)
+
+var (
+	ErrEmailNotVerified       = errors.New("verify your email before uploading images")
+	ErrEmailAlreadyVerified   = errors.New("the email is already verified")
+	ErrInvalidVerificationURL = errors.New("the verification link is invalid or has expired")
+)
+
+// sendVerificationEmail creates a one-time verification token for the user and emails them a link with it ,
+// the email is sent in the background so the request doesn't wait on the mail server
+func (uh *UserHandler) sendVerificationEmail(r *http.Request, user database.User) error {
+	if uh.AppURL == "" {
+		return errors.New("APP_URL is needed to send verification emails")
+	}
+	token, tokenHash, err := auth.NewVerificationToken()
+	if err != nil {
+		return err
+	}
+	if _, err := uh.Store.CreateEmailVerification(r.Context(), database.CreateEmailVerificationParams{
+		TokenHash: tokenHash,
+		UserID:    user.UserID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(uh.VerificationTTL),
+	}); err != nil {
+		return fmt.Errorf("unable to save the verification:%v", err)
+	}
+	link := strings.TrimSuffix(uh.AppURL, "/") + "/verify-email?token=" + token
+	body := fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s. If you didn't create an account you can ignore this email.\n", user.FullName, link, uh.VerificationTTL)
+	go func() {
+		if err := uh.Mailer.SendEmail(user.Email, "Verify your email", body); err != nil {
+			log.Printf("unable to send the verification email to user %d:%v", user.UserID, err)
+		}
+	}()
+	return nil
+}
+
+// handleVerifyEmail is the target of the verification link , the token can only be used once
+func (uh *UserHandler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, errors.New("the token is missing"))
+		return
+	}
+	user, err := uh.Store.VerifyEmail(r.Context(), auth.HashVerificationToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, ErrInvalidVerificationURL)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to verify the email"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "email verified",
+		Data:    models.NewUserResponse(user),
+	})
+}
+
+// handleResendVerificationEmail sends another verification link , links that were already sent stay valid until they expire
+func (uh *UserHandler) handleResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	user, err := uh.Store.GetUser(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the user"))
+		return
+	}
+	if user.VerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, ErrEmailAlreadyVerified)
+		return
+	}
+	if limited, err := uh.verificationLimited(r.Context(), user.UserID, verificationCooldown, 1); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	} else if limited {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", verificationCooldown.Seconds()))
+		respondWithError(w, http.StatusTooManyRequests, errors.New("wait a minute before asking for another verification email"))
+		return
+	}
+	if limited, err := uh.verificationLimited(r.Context(), user.UserID, 24*time.Hour, verificationDailyLimit); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	} else if limited {
+		respondWithError(w, http.StatusTooManyRequests, fmt.Errorf("only %d verification emails can be sent in a day", verificationDailyLimit))
+		return
+	}
+	if err := uh.sendVerificationEmail(r, user); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, APIResponse{
+		Status:  http.StatusAccepted,
+		Message: "verification email sent",
+	})
+}
+
+// verificationLimited reports whether the user already got limit verification emails in the window
+func (uh *UserHandler) verificationLimited(ctx context.Context, userId int64, window time.Duration, limit int64) (bool, error) {
+	sent, err := uh.Store.CountEmailVerificationsSince(ctx, database.CountEmailVerificationsSinceParams{
+		UserID:    userId,
+		CreatedAt: time.Now().Add(-window),
+	})
+	if err != nil {
+		return false, errors.New("unable to count the verification emails")
+	}
+	return sent >= limit, nil
+}
+
+// requireVerifiedEmail rejects users who haven't verified their email when RequireVerifiedEmail is set , it has to run after AuthMiddleware
+func (uh *UserHandler) requireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !uh.RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+		payload, err := getAuthPayload(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		user, err := uh.Store.GetUser(r.Context(), payload.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the user"))
+			return
+		}
+		if !user.VerifiedAt.Valid {
+			respondWithError(w, http.StatusForbidden, ErrEmailNotVerified)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/blobs"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgproc"
+	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/versions"
+	"github.com/mbeka02/image-service/internal/webhook"
+	"github.com/sqlc-dev/pqtype"
+)
+
+var errImageChanged = errors.New("the image was changed by someone else , reload it and try again")
+
+// handleReplaceImage stores new content for the image and archives the current one as a version.
+// a multipart body replaces it with the uploaded file , a JSON body applies transformations to the current content
+func (ih *ImageHandler) handleReplaceImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	var (
+		content         *imgstore.UploadResponse
+		metadata        *imgproc.ImageMetadata
+		transformations pqtype.NullRawMessage
+		err             error
+	)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("bad request:%v", err))
+			return
+		}
+		defer file.Close()
+		metadata, err = validateImage(file)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		content, err = ih.FileStorage.UploadStream(r.Context(), imgstore.OrgPrefix(image.OrgID)+versionFileName(image), metadata.ContentType, file)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("internal server error : %v", err))
+			return
+		}
+	} else {
+		request := models.TransformationsRequest{}
+		if err := parseAndValidateRequest(r, &request); err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		content, metadata, err = ih.transformContent(r.Context(), image, &request)
+		if err != nil {
+			respondWithJSON(w, http.StatusInternalServerError, APIError{
+				Message: "unable to perform the transformations",
+				Status:  http.StatusInternalServerError,
+				Detail:  err.Error(),
+			})
+			return
+		}
+		spec, err := json.Marshal(request)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		transformations = pqtype.NullRawMessage{RawMessage: spec, Valid: true}
+	}
+	content, err = blobs.Acquire(r.Context(), ih.Store, ih.FileStorage, image.OrgID, content)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rawMessage, err := metadata.Value()
+	if err != nil {
+		blobs.Release(r.Context(), ih.Store, ih.FileStorage, image.OrgID, content.ContentHash)
+		respondWithError(w, http.StatusInternalServerError, errors.New("failed to get image metadata"))
+		return
+	}
+	ih.respondWithReplacedImage(w, r, image, content, pqtype.NullRawMessage{RawMessage: rawMessage, Valid: true}, metadata.NullPerceptualHash(), transformations)
+}
+
+// transformContent applies the transformations to the current content of the image and uploads the result
+func (ih *ImageHandler) transformContent(ctx context.Context, image database.Image, request *models.TransformationsRequest) (*imgstore.UploadResponse, *imgproc.ImageMetadata, error) {
+	path, err := ih.FileStorage.DownloadTemp(ctx, image.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	output, err := ih.applyTransformations(path, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err := imgproc.ExtractMetadata(bytes.NewReader(output))
+	if err != nil {
+		// formats like webp can't be decoded by the std library , keep what we can sniff
+		metadata = &imgproc.ImageMetadata{ContentType: http.DetectContentType(output)}
+	}
+	content, err := ih.FileStorage.UploadStream(ctx, imgstore.OrgPrefix(image.OrgID)+versionFileName(image), metadata.ContentType, bytes.NewReader(output))
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, metadata, nil
+}
+
+func (ih *ImageHandler) handleGetImageVersions(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	archived, err := ih.Store.GetImageVersions(r.Context(), image.ImageID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the versions"))
+		return
+	}
+	if err := ih.signImage(r.Context(), &image); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for i := range archived {
+		if err := ih.signVersion(r.Context(), &archived[i]); err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "versions",
+		Data: models.ImageVersionsResponse{
+			Image:    image,
+			Versions: archived,
+		},
+	})
+}
+
+func (ih *ImageHandler) handleGetImageVersion(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessView)
+	if !ok {
+		return
+	}
+	version, ok := ih.getImageVersion(w, r, image)
+	if !ok {
+		return
+	}
+	if err := ih.signVersion(r.Context(), &version); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "version",
+		Data:    version,
+	})
+}
+
+// handleRevertImage makes the content of an archived version current again , the content it replaces is archived like any other edit
+func (ih *ImageHandler) handleRevertImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := ih.getAuthorizedImage(w, r, accessManage)
+	if !ok {
+		return
+	}
+	version, ok := ih.getImageVersion(w, r, image)
+	if !ok {
+		return
+	}
+	content, err := ih.versionContent(r.Context(), image, version)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ih.respondWithReplacedImage(w, r, image, content, version.Metadata, version.Phash, version.Transformations)
+}
+
+// versionContent takes a new reference to the content of the version so that it can become the current content again
+func (ih *ImageHandler) versionContent(ctx context.Context, image database.Image, version database.ImageVersion) (*imgstore.UploadResponse, error) {
+	if version.ContentHash.Valid {
+		blob, err := ih.Store.AcquireBlob(ctx, database.AcquireBlobParams{
+			OrgID:       image.OrgID,
+			ContentHash: version.ContentHash.String,
+			FileName:    version.FileName,
+			FileSize:    version.FileSize,
+			StorageUrl:  version.StorageUrl,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to save the blob:%v", err)
+		}
+		return &imgstore.UploadResponse{
+			FileName:    blob.FileName,
+			StorageUrl:  blob.StorageUrl,
+			Size:        blob.FileSize,
+			ContentHash: blob.ContentHash,
+		}, nil
+	}
+	// versions archived from images uploaded before content addressing own their object , it is copied so that the copy can be shared
+	reader, err := ih.FileStorage.Download(ctx, version.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download the version:%v", err)
+	}
+	defer reader.Close()
+	metadata := imgproc.ImageMetadata{}
+	if version.Metadata.Valid {
+		json.Unmarshal(version.Metadata.RawMessage, &metadata)
+	}
+	content, err := ih.FileStorage.UploadStream(ctx, imgstore.OrgPrefix(image.OrgID)+versionFileName(image), metadata.ContentType, reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy the version:%v", err)
+	}
+	return blobs.Acquire(ctx, ih.Store, ih.FileStorage, image.OrgID, content)
+}
+
+// respondWithReplacedImage makes the acquired content the current version of the image , prunes the versions over the limit and publishes the updated event
+func (ih *ImageHandler) respondWithReplacedImage(w http.ResponseWriter, r *http.Request, image database.Image, content *imgstore.UploadResponse, metadata pqtype.NullRawMessage, phash sql.NullInt64, transformations pqtype.NullRawMessage) {
+	replaced, err := ih.Store.ReplaceImageContent(r.Context(), database.ReplaceImageContentParams{
+		ImageID:         image.ImageID,
+		CurrentVersion:  image.Version,
+		FileName:        content.FileName,
+		FileSize:        content.Size,
+		StorageUrl:      content.StorageUrl,
+		Metadata:        metadata,
+		ContentHash:     sql.NullString{String: content.ContentHash, Valid: true},
+		Phash:           phash,
+		Transformations: transformations,
+	})
+	if err != nil {
+		blobs.Release(r.Context(), ih.Store, ih.FileStorage, image.OrgID, content.ContentHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, errImageChanged)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to save the new version"))
+		return
+	}
+	// the new version is saved , failing to prune only keeps some extra versions around until the next edit
+	if err := versions.Prune(r.Context(), ih.Store, ih.FileStorage, image.OrgID, image.ImageID, ih.MaxVersions); err != nil {
+		log.Printf("unable to prune the versions of image %d:%v", image.ImageID, err)
+	}
+	ih.publish(r.Context(), replaced.UserID, webhook.EventImageUpdated, replaced)
+	if err := ih.signImage(r.Context(), &replaced); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: fmt.Sprintf("saved version %d", replaced.Version),
+		Data:    replaced,
+	})
+}
+
+// getImageVersion loads the archived version in the url , it writes the error response and returns false when there is none
+func (ih *ImageHandler) getImageVersion(w http.ResponseWriter, r *http.Request, image database.Image) (database.ImageVersion, bool) {
+	number, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 32)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.ImageVersion{}, false
+	}
+	if int32(number) == image.Version {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("version %d is the current version", number))
+		return database.ImageVersion{}, false
+	}
+	version, err := ih.Store.GetImageVersion(r.Context(), database.GetImageVersionParams{
+		ImageID: image.ImageID,
+		Version: int32(number),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("version not found"))
+			return database.ImageVersion{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to get the version"))
+		return database.ImageVersion{}, false
+	}
+	return version, true
+}
+
+// signVersion is signImage for archived versions
+func (ih *ImageHandler) signVersion(ctx context.Context, version *database.ImageVersion) error {
+	url, err := ih.FileStorage.SignedURL(ctx, version.FileName, ih.DownloadExpiry)
+	if err != nil {
+		return err
+	}
+	version.StorageUrl = url
+	version.Metadata = ih.redactMetadata(version.Metadata)
+	return nil
+}
+
+// versionFileName names the object of the next version of the image , the storage adds a unique suffix
+func versionFileName(image database.Image) string {
+	return fmt.Sprintf("image_%d_v%d", image.ImageID, image.Version+1)
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
+	"github.com/mbeka02/image-service/internal/models"
+	"github.com/mbeka02/image-service/internal/webhook"
+)
+
+type WebhookHandler struct {
+	Store *database.Store
+}
+
+func (wh *WebhookHandler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	request := models.CreateWebhookRequest{}
+	if err := parseAndValidateRequest(r, &request); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	// the address itself is checked by the dispatcher when it connects since the host can resolve to something else later
+	if err := fetch.CheckURL(request.Url); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to generate the webhook secret"))
+		return
+	}
+	createdWebhook, err := wh.Store.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		UserID: payload.UserID,
+		Url:    request.Url,
+		Secret: secret,
+		Events: request.Events,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to create the webhook"))
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, APIResponse{
+		Status:  http.StatusCreated,
+		Message: "webhook created",
+		Data: models.CreateWebhookResponse{
+			WebhookResponse: models.NewWebhookResponse(createdWebhook),
+			Secret:          createdWebhook.Secret,
+		},
+	})
+}
+
+func (wh *WebhookHandler) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	webhooks, err := wh.Store.GetUserWebhooks(r.Context(), payload.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data := make([]models.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		data = append(data, models.NewWebhookResponse(webhook))
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "webhooks",
+		Data:    data,
+	})
+}
+
+func (wh *WebhookHandler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.getUserWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := wh.Store.DeleteUserWebhook(r.Context(), database.DeleteUserWebhookParams{
+		WebhookID: webhook.WebhookID,
+		UserID:    webhook.UserID,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to delete the webhook"))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "deleted the webhook sucessfully",
+	})
+}
+
+func (wh *WebhookHandler) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.getUserWebhook(w, r)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10 // default limit
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 // default offset
+	}
+	deliveries, err := wh.Store.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		WebhookID: webhook.WebhookID,
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, APIResponse{
+		Status:  http.StatusOK,
+		Message: "deliveries",
+		Data:    deliveries,
+	})
+}
+
+// handleRedeliver queues a fresh copy of a past delivery , the original entry is kept in the delivery log
+func (wh *WebhookHandler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.getUserWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return
+	}
+	delivery, err := wh.Store.GetWebhookDelivery(r.Context(), deliveryId)
+	if err != nil || delivery.WebhookID != webhook.WebhookID {
+		respondWithError(w, http.StatusNotFound, errors.New("unable to find the delivery"))
+		return
+	}
+	redelivery, err := wh.Store.CreateWebhookDelivery(r.Context(), database.CreateWebhookDeliveryParams{
+		WebhookID: delivery.WebhookID,
+		Event:     delivery.Event,
+		Payload:   delivery.Payload,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, errors.New("unable to queue the delivery"))
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, APIResponse{
+		Status:  http.StatusAccepted,
+		Message: "redelivery queued",
+		Data:    redelivery,
+	})
+}
+
+// getUserWebhook loads the webhook in the url and makes sure it belongs to the caller , it writes the error response itself
+func (wh *WebhookHandler) getUserWebhook(w http.ResponseWriter, r *http.Request) (database.Webhook, bool) {
+	webhookId, err := strconv.ParseInt(chi.URLParam(r, "webhookId"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("invalid url param"))
+		return database.Webhook{}, false
+	}
+	payload, err := getAuthPayload(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return database.Webhook{}, false
+	}
+	webhook, err := wh.Store.GetWebhook(r.Context(), webhookId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, errors.New("unable to get webhook"))
+		return database.Webhook{}, false
+	}
+	if webhook.UserID != payload.UserID {
+		respondWithError(w, http.StatusUnauthorized, errors.New("unauthorized!"))
+		return database.Webhook{}, false
+	}
+	return webhook, true
+}
@@ -0,0 +1,103 @@
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/blobs"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgstore"
+	"github.com/mbeka02/image-service/internal/versions"
+)
+
+const (
+	purgeInterval = time.Hour
+	purgeBatch    = 100
+)
+
+// Purger permanently deletes the images that have been in the trash for longer than the retention period
+type Purger struct {
+	Store       *database.Store
+	FileStorage imgstore.Storage
+	Retention   time.Duration
+}
+
+func NewPurger(store *database.Store, fileStorage imgstore.Storage, retention time.Duration) *Purger {
+	return &Purger{
+		Store:       store,
+		FileStorage: fileStorage,
+		Retention:   retention,
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.purge(ctx)
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	for {
+		expired, err := p.Store.GetExpiredTrash(ctx, database.GetExpiredTrashParams{
+			DeletedAt: sql.NullTime{Time: time.Now().Add(-p.Retention), Valid: true},
+			Limit:     purgeBatch,
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("unable to get expired trash:%v", err)
+			}
+			return
+		}
+		for _, image := range expired {
+			if err := Purge(ctx, p.Store, p.FileStorage, image); err != nil {
+				log.Printf("unable to purge image %d:%v", image.ImageID, err)
+				return
+			}
+		}
+		if len(expired) < purgeBatch {
+			return
+		}
+	}
+}
+
+// Purge removes the image row , the objects are only deleted once no other image or version references their content
+func Purge(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, image database.Image) error {
+	// the versions are deleted together with the image
+	archived, err := store.GetImageVersions(ctx, image.ImageID)
+	if err != nil {
+		return err
+	}
+	// images uploaded before content addressing own their object
+	if !image.ContentHash.Valid {
+		if err := fileStorage.Delete(ctx, image.FileName); err != nil {
+			return err
+		}
+	}
+	if err := store.DeleteOrgImage(ctx, database.DeleteOrgImageParams{
+		OrgID:   image.OrgID,
+		ImageID: image.ImageID,
+	}); err != nil {
+		return err
+	}
+	if image.ContentHash.Valid {
+		if err := blobs.Release(ctx, store, fileStorage, image.OrgID, image.ContentHash.String); err != nil {
+			return err
+		}
+	}
+	for _, version := range archived {
+		if err := versions.Release(ctx, store, fileStorage, image.OrgID, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package uploads
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgstore"
+)
+
+const (
+	// Expiration is how long a client has to finish a resumable upload once it has been created
+	Expiration    = 24 * time.Hour
+	cleanInterval = 10 * time.Minute
+	cleanBatch    = 100
+)
+
+// Cleaner removes expired resumable uploads together with the chunks they left in storage
+type Cleaner struct {
+	Store       *database.Store
+	FileStorage imgstore.Storage
+}
+
+func NewCleaner(store *database.Store, fileStorage imgstore.Storage) *Cleaner {
+	return &Cleaner{
+		Store:       store,
+		FileStorage: fileStorage,
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (c *Cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.clean(ctx)
+		}
+	}
+}
+
+func (c *Cleaner) clean(ctx context.Context) {
+	for {
+		expired, err := c.Store.GetExpiredUploads(ctx, database.GetExpiredUploadsParams{
+			ExpiresAt: time.Now(),
+			Limit:     cleanBatch,
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("unable to get expired uploads:%v", err)
+			}
+			return
+		}
+		for _, upload := range expired {
+			if err := Delete(ctx, c.Store, c.FileStorage, upload); err != nil {
+				log.Printf("unable to delete expired upload %s:%v", upload.UploadID, err)
+				return
+			}
+		}
+		if len(expired) < cleanBatch {
+			return
+		}
+	}
+}
+
+// DeleteChunks removes the stored chunks of an upload , missing chunks are ignored so that it can be retried
+func DeleteChunks(ctx context.Context, fileStorage imgstore.Storage, upload database.Upload) {
+	for _, chunk := range upload.Chunks {
+		if err := fileStorage.Delete(ctx, chunk); err != nil {
+			log.Printf("unable to delete chunk %s of upload %s:%v", chunk, upload.UploadID, err)
+		}
+	}
+}
+
+// Delete removes the chunks of an upload and then the upload itself
+func Delete(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, upload database.Upload) error {
+	DeleteChunks(ctx, fileStorage, upload)
+	return store.DeleteUpload(ctx, upload.UploadID)
+}
@@ -0,0 +1,36 @@
+package versions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mbeka02/image-service/internal/blobs"
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/imgstore"
+)
+
+// Release drops the reference the version holds to its content
+func Release(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, orgId int64, version database.ImageVersion) error {
+	// versions archived from images uploaded before content addressing own their object
+	if !version.ContentHash.Valid {
+		return fileStorage.Delete(ctx, version.FileName)
+	}
+	return blobs.Release(ctx, store, fileStorage, orgId, version.ContentHash.String)
+}
+
+// Prune deletes all but the newest keep versions of the image and releases their content from the organization of the image
+func Prune(ctx context.Context, store *database.Store, fileStorage imgstore.Storage, orgId, imageId int64, keep int) error {
+	pruned, err := store.DeleteOldImageVersions(ctx, database.DeleteOldImageVersionsParams{
+		ImageID: imageId,
+		Keep:    int32(keep),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to prune the versions:%v", err)
+	}
+	for _, version := range pruned {
+		if err := Release(ctx, store, fileStorage, orgId, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
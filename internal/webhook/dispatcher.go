@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
+)
+
+const (
+	EventImageUploaded    = "image.uploaded"
+	EventImageTransformed = "image.transformed"
+	EventImageDeleted     = "image.deleted"
+	EventImageUpdated     = "image.updated"
+	EventJobCompleted     = "job.completed"
+	EventJobFailed        = "job.failed"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	maxAttempts     = 8
+	pollInterval    = 2 * time.Second
+	deliveryTimeout = 10 * time.Second
+	// how long a claimed delivery is reserved before another dispatcher may pick it up
+	claimLease  = time.Minute
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Event is the JSON body that is posted to the webhook endpoints
+type Event struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher records events for the subscribed webhooks and delivers them in the background
+type Dispatcher struct {
+	Store  *database.Store
+	Client *http.Client
+}
+
+// NewDispatcher delivers with a client that can't reach private , loopback and link-local addresses unless they are in allowedNetworks ,
+// receivers are expected to answer directly so redirects are not followed
+func NewDispatcher(store *database.Store, allowedNetworks []*net.IPNet) *Dispatcher {
+	return &Dispatcher{
+		Store: store,
+		Client: fetch.NewClient(fetch.Options{
+			Timeout:         deliveryTimeout,
+			MaxRedirects:    0,
+			AllowedNetworks: allowedNetworks,
+		}),
+	}
+}
+
+// Publish queues a delivery of the event for every webhook of the user that is subscribed to it
+func (d *Dispatcher) Publish(ctx context.Context, userId int64, eventType string, data interface{}) error {
+	webhooks, err := d.Store.GetSubscribedWebhooks(ctx, database.GetSubscribedWebhooksParams{
+		UserID: userId,
+		Event:  eventType,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get the subscribed webhooks:%v", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Event{
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode the event:%v", err)
+	}
+	for _, webhook := range webhooks {
+		if _, err := d.Store.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			WebhookID: webhook.WebhookID,
+			Event:     eventType,
+			Payload:   payload,
+		}); err != nil {
+			return fmt.Errorf("unable to queue the delivery:%v", err)
+		}
+	}
+	return nil
+}
+
+// Run delivers pending events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		delivery, err := d.Store.ClaimWebhookDelivery(ctx, time.Now().Add(claimLease))
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) && ctx.Err() == nil {
+				log.Printf("unable to claim a webhook delivery:%v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.WebhookDelivery) {
+	responseStatus, err := d.send(ctx, delivery)
+	status := sql.NullInt32{Int32: int32(responseStatus), Valid: responseStatus != 0}
+	// the outcome is persisted even if the dispatcher is shutting down
+	bookkeepingCtx := context.Background()
+	if err == nil {
+		if err := d.Store.MarkWebhookDelivered(bookkeepingCtx, database.MarkWebhookDeliveredParams{
+			DeliveryID:     delivery.DeliveryID,
+			ResponseStatus: status,
+		}); err != nil {
+			log.Printf("unable to mark webhook delivery %d as delivered:%v", delivery.DeliveryID, err)
+		}
+		return
+	}
+
+	lastError := sql.NullString{String: err.Error(), Valid: true}
+	if delivery.Attempts >= maxAttempts {
+		if err := d.Store.FailWebhookDelivery(bookkeepingCtx, database.FailWebhookDeliveryParams{
+			DeliveryID:     delivery.DeliveryID,
+			ResponseStatus: status,
+			LastError:      lastError,
+		}); err != nil {
+			log.Printf("unable to fail webhook delivery %d:%v", delivery.DeliveryID, err)
+		}
+		return
+	}
+	if err := d.Store.RetryWebhookDelivery(bookkeepingCtx, database.RetryWebhookDeliveryParams{
+		DeliveryID:     delivery.DeliveryID,
+		ResponseStatus: status,
+		LastError:      lastError,
+		NextAttemptAt:  time.Now().Add(backoff(delivery.Attempts)),
+	}); err != nil {
+		log.Printf("unable to reschedule webhook delivery %d:%v", delivery.DeliveryID, err)
+	}
+}
+
+// send posts the signed payload to the webhook url , any non 2xx response is treated as a failure
+func (d *Dispatcher) send(ctx context.Context, delivery database.WebhookDelivery) (int, error) {
+	webhook, err := d.Store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the webhook:%v", err)
+	}
+	return d.post(ctx, webhook, delivery)
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook database.Webhook, delivery database.WebhookDelivery) (int, error) {
+	if err := fetch.CheckURL(webhook.Url); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request:%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.DeliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, delivery.Payload))
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to reach the webhook:%w", err)
+	}
+	defer res.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("the webhook responded with %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign returns the value of the signature header , receivers recompute the HMAC-SHA256 of the raw body with their secret and compare
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoff(attempt int32) time.Duration {
+	delay := baseBackoff
+	for i := int32(1); i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// NewSecret generates the random secret used to sign the payloads of a webhook
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mbeka02/image-service/internal/database"
+	"github.com/mbeka02/image-service/internal/fetch"
+)
+
+var loopback = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}}
+
+func TestPostSignsTheDelivery(t *testing.T) {
+	delivery := database.WebhookDelivery{DeliveryID: 7, Event: EventImageUploaded, Payload: []byte(`{"type":"image.uploaded"}`)}
+	received := make(chan *http.Request, 1)
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil, loopback)
+	status, err := d.post(context.Background(), database.Webhook{Url: receiver.URL, Secret: "secret"}, delivery)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("got status %d , want %d", status, http.StatusNoContent)
+	}
+	r := <-received
+	if string(body) != string(delivery.Payload) {
+		t.Errorf("got body %s", body)
+	}
+	if got, want := r.Header.Get(SignatureHeader), Sign("secret", delivery.Payload); got != want {
+		t.Errorf("got signature %s , want %s", got, want)
+	}
+	if r.Header.Get(EventHeader) != EventImageUploaded || r.Header.Get(DeliveryHeader) != "7" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+}
+
+func TestPostFailsOnErrorResponses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil, loopback)
+	status, err := d.post(context.Background(), database.Webhook{Url: receiver.URL}, database.WebhookDelivery{})
+	if err == nil || status != http.StatusInternalServerError {
+		t.Errorf("got status %d and error %v , want a failed 500", status, err)
+	}
+}
+
+func TestPostRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	// without the loopback network in the allowlist the receiver is an internal service
+	d := NewDispatcher(nil, nil)
+	_, err := d.post(context.Background(), database.Webhook{Url: receiver.URL}, database.WebhookDelivery{})
+	if !errors.Is(err, fetch.ErrForbiddenAddress) {
+		t.Errorf("got %v , want %v", err, fetch.ErrForbiddenAddress)
+	}
+	if called {
+		t.Error("the receiver was called")
+	}
+
+	for _, target := range []string{"http://169.254.169.254/latest/meta-data", "http://10.0.0.1/", "http://[::1]/"} {
+		if _, err := d.post(context.Background(), database.Webhook{Url: target}, database.WebhookDelivery{}); !errors.Is(err, fetch.ErrForbiddenAddress) {
+			t.Errorf("%s: got %v , want %v", target, err, fetch.ErrForbiddenAddress)
+		}
+	}
+}
+
+func TestPostDoesNotFollowRedirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the redirect was followed")
+	}))
+	defer internal.Close()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(nil, loopback)
+	if _, err := d.post(context.Background(), database.Webhook{Url: receiver.URL}, database.WebhookDelivery{}); !errors.Is(err, fetch.ErrTooManyRedirects) {
+		t.Errorf("got %v , want %v", err, fetch.ErrTooManyRedirects)
+	}
+}
+
+func TestPostRejectsOtherSchemes(t *testing.T) {
+	d := NewDispatcher(nil, loopback)
+	if _, err := d.post(context.Background(), database.Webhook{Url: "file:///etc/passwd"}, database.WebhookDelivery{}); !errors.Is(err, fetch.ErrUnsupportedURL) {
+		t.Errorf("got %v , want %v", err, fetch.ErrUnsupportedURL)
+	}
+}
@@ -0,0 +1,37 @@
+-- name: CreateAlbum :one
+INSERT INTO albums(user_id , org_id , title , description) VALUES ($1,$2,$3,$4) RETURNING *;
+-- name: GetAlbum :one
+SELECT * FROM albums WHERE album_id=$1;
+-- name: GetOrgAlbums :many
+SELECT * FROM albums WHERE org_id=$1 ORDER BY created_at DESC , album_id DESC;
+-- name: UpdateAlbum :one
+UPDATE albums SET title=coalesce(sqlc.narg(title) , title) , description=coalesce(sqlc.narg(description) , description) , updated_at=now()
+WHERE album_id=sqlc.arg(album_id) RETURNING *;
+-- name: SetAlbumCover :one
+-- the cover has to be one of the images of the album , a null cover removes it
+UPDATE albums SET cover_image_id=sqlc.narg(cover_image_id) , updated_at=now()
+WHERE album_id=sqlc.arg(album_id) AND (sqlc.narg(cover_image_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.album_id=albums.album_id AND album_images.image_id=sqlc.narg(cover_image_id)))
+RETURNING *;
+-- name: DeleteAlbum :exec
+DELETE FROM albums WHERE album_id=$1;
+-- name: AddImagesToAlbum :execrows
+-- images of other organizations are skipped , the others are appended in the order of image_ids.
+-- callers have to check that the user can manage the images since grants on the album cover them
+INSERT INTO album_images(album_id , image_id , position)
+SELECT sqlc.arg(album_id)::bigint , images.image_id , (SELECT coalesce(max(position) , 0) FROM album_images WHERE album_id=sqlc.arg(album_id)) + (row_number() OVER (ORDER BY array_position(sqlc.arg(image_ids)::bigint[] , images.image_id)))::int
+FROM images WHERE images.org_id=sqlc.arg(org_id) AND images.deleted_at IS NULL AND images.image_id=ANY(sqlc.arg(image_ids)::bigint[])
+ON CONFLICT DO NOTHING;
+-- name: RemoveImagesFromAlbum :execrows
+DELETE FROM album_images WHERE album_id=$1 AND image_id=ANY($2::bigint[]);
+-- name: ReorderAlbumImages :execrows
+-- the images in image_ids move to the front in that order , the others follow in their current order
+UPDATE album_images SET position=ranked.position
+FROM (SELECT image_id , (row_number() OVER (ORDER BY array_position(sqlc.arg(image_ids)::bigint[] , image_id) NULLS LAST , position , image_id))::int AS position
+FROM album_images WHERE album_id=sqlc.arg(album_id)) AS ranked
+WHERE album_images.album_id=sqlc.arg(album_id) AND album_images.image_id=ranked.image_id;
+-- name: GetAlbumImages :many
+SELECT images.* FROM images JOIN album_images ON album_images.image_id=images.image_id
+WHERE album_images.album_id=$1 AND images.deleted_at IS NULL ORDER BY album_images.position , images.image_id LIMIT $2 OFFSET $3;
+-- name: GetAlbumImage :one
+SELECT images.* FROM images JOIN album_images ON album_images.image_id=images.image_id
+WHERE album_images.album_id=$1 AND images.image_id=$2 AND images.deleted_at IS NULL;
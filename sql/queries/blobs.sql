@@ -0,0 +1,12 @@
+-- name: AcquireBlob :one
+-- the first upload of some content to the organization becomes the blob , later uploads only add a reference to it
+INSERT INTO blobs(org_id , content_hash , file_name , file_size , storage_url , ref_count) VALUES ($1,$2,$3,$4,$5,1)
+ON CONFLICT (org_id, content_hash) DO UPDATE SET ref_count=blobs.ref_count+1
+RETURNING *;
+-- name: ReleaseBlob :one
+UPDATE blobs SET ref_count=ref_count-1 WHERE org_id=$1 AND content_hash=$2 RETURNING *;
+-- name: DeleteUnreferencedBlob :execrows
+DELETE FROM blobs WHERE org_id=$1 AND content_hash=$2 AND ref_count<=0;
+-- name: CountBlobsByFileName :one
+-- blobs migrated from before content was scoped to organizations can share an object
+SELECT count(*) FROM blobs WHERE file_name=$1;
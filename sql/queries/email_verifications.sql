@@ -0,0 +1,11 @@
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications(token_hash , user_id , email , expires_at) VALUES ($1,$2,$3,$4) RETURNING *;
+-- name: CountEmailVerificationsSince :one
+SELECT count(*) FROM email_verifications WHERE user_id=$1 AND created_at>$2;
+-- name: VerifyEmail :one
+-- the token is used up and the user verified in one statement , nothing is returned when the token is unknown , used or expired
+WITH verification AS (
+UPDATE email_verifications SET used_at=now() WHERE token_hash=$1 AND used_at IS NULL AND expires_at>now() RETURNING user_id , email
+)
+UPDATE users SET verified_at=COALESCE(users.verified_at , now()) FROM verification
+WHERE users.user_id=verification.user_id AND users.email=verification.email RETURNING users.*;
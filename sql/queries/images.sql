@@ -1,9 +1,180 @@
 -- name: CreateImage :one
-INSERT INTO images(user_id , file_name , file_size , storage_url , metadata) VALUES ($1,$2,$3,$4,$5)RETURNING file_name , file_size , storage_url;
+INSERT INTO images(user_id , org_id , file_name , file_size , storage_url , metadata , content_hash , phash) VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING *;
 
--- name: GetUserImages :many
-SELECT * FROM images WHERE user_id=$1 LIMIT $2 OFFSET $3;
+-- name: GetOrgImages :many
+SELECT * FROM images WHERE org_id=$1 AND deleted_at IS NULL ORDER BY image_id LIMIT $2 OFFSET $3;
+-- name: CountOrgImages :one
+-- takes the same filters as the ListOrgImagesBy queries
+SELECT count(*) FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)));
+-- name: ListOrgImagesByCreatedAtAsc :many
+-- sorted by created_at with the image id breaking ties , the cursor is the created_at and id of the last image of the previous page
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (created_at , image_id)>(sqlc.narg(cursor_created_at)::timestamptz , sqlc.narg(cursor_id)))
+ORDER BY created_at , image_id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+-- name: ListOrgImagesByCreatedAtDesc :many
+-- see ListOrgImagesByCreatedAtAsc
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (created_at , image_id)<(sqlc.narg(cursor_created_at)::timestamptz , sqlc.narg(cursor_id)))
+ORDER BY created_at DESC , image_id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+-- name: ListOrgImagesByFileSizeAsc :many
+-- sorted by file_size with the image id breaking ties , the cursor is the file_size and id of the last image of the previous page
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (file_size , image_id)>(sqlc.narg(cursor_file_size)::bigint , sqlc.narg(cursor_id)))
+ORDER BY file_size , image_id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+-- name: ListOrgImagesByFileSizeDesc :many
+-- see ListOrgImagesByFileSizeAsc
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (file_size , image_id)<(sqlc.narg(cursor_file_size)::bigint , sqlc.narg(cursor_id)))
+ORDER BY file_size DESC , image_id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+-- name: ListOrgImagesByNameAsc :many
+-- sorted by name with the image id breaking ties , the cursor is the file_name and id of the last image of the previous page
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (file_name , image_id)>(sqlc.narg(cursor_name)::text , sqlc.narg(cursor_id)))
+ORDER BY file_name , image_id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+-- name: ListOrgImagesByNameDesc :many
+-- see ListOrgImagesByNameAsc
+SELECT * FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL
+AND (sqlc.narg(content_type)::text IS NULL OR metadata->>'content_type'=sqlc.narg(content_type))
+AND (sqlc.narg(min_width)::int IS NULL OR (metadata->>'width')::int>=sqlc.narg(min_width))
+AND (sqlc.narg(max_width)::int IS NULL OR (metadata->>'width')::int<=sqlc.narg(max_width))
+AND (sqlc.narg(min_height)::int IS NULL OR (metadata->>'height')::int>=sqlc.narg(min_height))
+AND (sqlc.narg(max_height)::int IS NULL OR (metadata->>'height')::int<=sqlc.narg(max_height))
+AND (sqlc.narg(min_size)::bigint IS NULL OR file_size>=sqlc.narg(min_size))
+AND (sqlc.narg(max_size)::bigint IS NULL OR file_size<=sqlc.narg(max_size))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at>=sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at<sqlc.narg(created_before))
+AND (sqlc.narg(name)::text IS NULL OR file_name ILIKE '%' || sqlc.narg(name) || '%')
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (SELECT 1 FROM image_tags JOIN tags ON tags.tag_id=image_tags.tag_id WHERE image_tags.image_id=images.image_id AND tags.name=sqlc.narg(tag)))
+AND (sqlc.narg(attributes)::jsonb IS NULL OR attributes @> sqlc.narg(attributes))
+AND (sqlc.narg(album_id)::bigint IS NULL OR EXISTS (SELECT 1 FROM album_images WHERE album_images.image_id=images.image_id AND album_images.album_id=sqlc.narg(album_id)))
+AND (sqlc.narg(cursor_id)::bigint IS NULL OR (file_name , image_id)<(sqlc.narg(cursor_name)::text , sqlc.narg(cursor_id)))
+ORDER BY file_name DESC , image_id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
 -- name: GetImage :one
-SELECT * FROM images WHERE image_id=$1;
--- name: DeleteUserImage :exec
-DELETE FROM images WHERE image_id=$1 AND user_id=$2; 
+-- images in the trash are left out , see GetTrashedImage
+SELECT * FROM images WHERE image_id=$1 AND deleted_at IS NULL;
+-- name: DeleteOrgImage :exec
+DELETE FROM images WHERE image_id=$1 AND org_id=$2; 
+-- name: GetOrgImageByHash :one
+SELECT * FROM images WHERE org_id=$1 AND content_hash=$2 AND deleted_at IS NULL ORDER BY image_id LIMIT 1;
+-- name: GetSimilarImages :many
+-- the distance is the number of bits that differ between the perceptual hashes
+SELECT * , length(replace((phash # sqlc.arg(phash)::bigint)::bit(64)::text , '0' , ''))::int AS distance FROM images
+WHERE org_id=sqlc.arg(org_id) AND image_id<>sqlc.arg(image_id) AND deleted_at IS NULL AND phash IS NOT NULL AND length(replace((phash # sqlc.arg(phash)::bigint)::bit(64)::text , '0' , ''))<=sqlc.arg(max_distance)::int
+ORDER BY distance , image_id LIMIT sqlc.arg(max_results);
+-- name: GetImagesWithoutPhash :many
+SELECT * FROM images WHERE phash IS NULL AND image_id>$1 ORDER BY image_id LIMIT $2;
+-- name: SetImagePhash :exec
+UPDATE images SET phash=$2 WHERE image_id=$1;
+-- name: UpdateImageAttributes :one
+-- the keys in remove_keys are deleted before set_attributes is merged in
+UPDATE images SET attributes=(attributes - sqlc.arg(remove_keys)::text[]) || sqlc.arg(set_attributes)::jsonb , updated_at=now() WHERE image_id=sqlc.arg(image_id) RETURNING *;
+-- name: SetImageVisibility :one
+-- the token of an unlisted image is kept until it stops being unlisted , the next time it is unlisted it gets a new one
+UPDATE images SET visibility=sqlc.arg(visibility) ,
+unlisted_token=CASE WHEN sqlc.arg(visibility)='unlisted' THEN coalesce(unlisted_token , sqlc.arg(unlisted_token)) END , updated_at=now()
+WHERE image_id=sqlc.arg(image_id) RETURNING *;
+-- name: GetUnlistedImage :one
+SELECT * FROM images WHERE unlisted_token=$1 AND visibility='unlisted' AND deleted_at IS NULL;
+-- name: GetPublicImages :many
+SELECT * FROM images WHERE visibility='public' AND deleted_at IS NULL ORDER BY created_at DESC , image_id DESC LIMIT $1 OFFSET $2;
+-- name: TrashImage :one
+UPDATE images SET deleted_at=now() WHERE image_id=$1 AND deleted_at IS NULL RETURNING *;
+-- name: RestoreImage :one
+UPDATE images SET deleted_at=NULL , updated_at=now() WHERE image_id=$1 AND deleted_at IS NOT NULL RETURNING *;
+-- name: GetTrashedImage :one
+SELECT * FROM images WHERE image_id=$1 AND deleted_at IS NOT NULL;
+-- name: GetTrashedImages :many
+SELECT * FROM images WHERE org_id=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC , image_id DESC LIMIT $2 OFFSET $3;
+-- name: GetExpiredTrash :many
+SELECT * FROM images WHERE deleted_at<$1 ORDER BY deleted_at , image_id LIMIT $2;
@@ -0,0 +1,29 @@
+-- name: CreateJob :one
+INSERT INTO jobs(user_id , org_id , image_id , spec , max_attempts) VALUES ($1,$2,$3,$4,$5) RETURNING *;
+-- name: GetJob :one
+SELECT * FROM jobs WHERE job_id=$1;
+-- name: GetUserJobs :many
+SELECT * FROM jobs WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+-- name: ClaimJob :one
+-- jobs that used up their attempts are never claimed again , even if they were left pending
+UPDATE jobs SET status='running' , attempts=attempts+1 , updated_at=now()
+WHERE job_id=(SELECT job_id FROM jobs WHERE status='pending' AND run_at<=now() AND attempts<max_attempts ORDER BY run_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING *;
+-- name: CompleteJob :execrows
+-- the attempt a worker claimed is its lease on the job , CompleteJob , RetryJob and FailJob do nothing once the job was released and claimed again
+UPDATE jobs SET status='completed' , result_image_id=$3 , last_error=NULL , updated_at=now() WHERE job_id=$1 AND attempts=$2 AND status='running';
+-- name: RetryJob :execrows
+UPDATE jobs SET status='pending' , last_error=$3 , run_at=$4 , updated_at=now() WHERE job_id=$1 AND attempts=$2 AND status='running';
+-- name: FailJob :execrows
+UPDATE jobs SET status='failed' , last_error=$3 , updated_at=now() WHERE job_id=$1 AND attempts=$2 AND status='running';
+-- name: TouchJob :execrows
+-- the heartbeat of a running job , it keeps ReleaseStaleJobs away from jobs that are still being worked on
+UPDATE jobs SET updated_at=now() WHERE job_id=$1 AND attempts=$2 AND status='running';
+-- name: ReleaseJob :exec
+-- puts a job that was interrupted by a shutdown back in the queue without counting the attempt
+UPDATE jobs SET status='pending' , attempts=attempts-1 , run_at=now() , updated_at=now() WHERE job_id=$1 AND attempts=$2 AND status='running';
+-- name: ReleaseStaleJobs :execrows
+-- a job that keeps crashing its worker would never get to fail on its own , it is failed once it used up its attempts
+UPDATE jobs SET status=CASE WHEN attempts>=max_attempts THEN 'failed' ELSE 'pending' END ,
+last_error=CASE WHEN attempts>=max_attempts THEN 'the worker stopped while running the job' ELSE last_error END , updated_at=now()
+WHERE status='running' AND updated_at<$1;
@@ -0,0 +1,27 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations(name , personal_user_id) VALUES ($1,$2) RETURNING *;
+-- name: GetOrganization :one
+SELECT * FROM organizations WHERE org_id=$1;
+-- name: GetPersonalOrganization :one
+SELECT * FROM organizations WHERE personal_user_id=$1;
+-- name: GetUserOrganizations :many
+SELECT organizations.* , org_members.role FROM organizations JOIN org_members ON org_members.org_id=organizations.org_id
+WHERE org_members.user_id=$1 ORDER BY organizations.personal_user_id IS NULL , organizations.name , organizations.org_id;
+-- name: UpdateOrganization :one
+UPDATE organizations SET name=$2 WHERE org_id=$1 RETURNING *;
+-- name: DeleteOrganization :execrows
+-- organizations that still own images can't be deleted
+DELETE FROM organizations WHERE org_id=$1 AND personal_user_id IS NULL AND NOT EXISTS (SELECT 1 FROM images WHERE images.org_id=organizations.org_id);
+-- name: AddOrgMember :one
+INSERT INTO org_members(org_id , user_id , role) VALUES ($1,$2,$3)
+ON CONFLICT (org_id , user_id) DO UPDATE SET role=EXCLUDED.role
+RETURNING *;
+-- name: GetOrgMember :one
+SELECT * FROM org_members WHERE org_id=$1 AND user_id=$2;
+-- name: GetOrgMembers :many
+SELECT org_members.user_id , users.email , users.full_name , org_members.role , org_members.created_at FROM org_members
+JOIN users ON users.user_id=org_members.user_id WHERE org_members.org_id=$1 ORDER BY org_members.created_at , org_members.user_id;
+-- name: RemoveOrgMember :execrows
+DELETE FROM org_members WHERE org_id=$1 AND user_id=$2;
+-- name: CountOrgOwners :one
+SELECT count(*) FROM org_members WHERE org_id=$1 AND role='owner';
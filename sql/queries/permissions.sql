@@ -0,0 +1,31 @@
+-- name: GrantImagePermission :one
+INSERT INTO permissions(image_id , grantee_id , granted_by , level) VALUES ($1,$2,$3,$4)
+ON CONFLICT (image_id , grantee_id) WHERE image_id IS NOT NULL DO UPDATE SET level=EXCLUDED.level , granted_by=EXCLUDED.granted_by
+RETURNING *;
+-- name: GrantAlbumPermission :one
+INSERT INTO permissions(album_id , grantee_id , granted_by , level) VALUES ($1,$2,$3,$4)
+ON CONFLICT (album_id , grantee_id) WHERE album_id IS NOT NULL DO UPDATE SET level=EXCLUDED.level , granted_by=EXCLUDED.granted_by
+RETURNING *;
+-- name: RevokeImagePermission :execrows
+DELETE FROM permissions WHERE image_id=$1 AND grantee_id=$2;
+-- name: RevokeAlbumPermission :execrows
+DELETE FROM permissions WHERE album_id=$1 AND grantee_id=$2;
+-- name: GetImagePermissions :many
+SELECT permissions.permission_id , permissions.grantee_id , users.email , users.full_name , permissions.level , permissions.created_at FROM permissions
+JOIN users ON users.user_id=permissions.grantee_id WHERE permissions.image_id=$1 ORDER BY permissions.created_at;
+-- name: GetAlbumPermissions :many
+SELECT permissions.permission_id , permissions.grantee_id , users.email , users.full_name , permissions.level , permissions.created_at FROM permissions
+JOIN users ON users.user_id=permissions.grantee_id WHERE permissions.album_id=$1 ORDER BY permissions.created_at;
+-- name: GetImageAccessLevel :one
+-- the highest level the user was granted on the image , directly or through one of its albums , 0 means none
+SELECT coalesce(max(CASE level WHEN 'manage' THEN 3 WHEN 'transform' THEN 2 WHEN 'view' THEN 1 END) , 0)::int AS access_level FROM permissions
+WHERE grantee_id=sqlc.arg(grantee_id) AND (image_id=sqlc.arg(image_id) OR album_id IN (SELECT album_id FROM album_images WHERE album_images.image_id=sqlc.arg(image_id)));
+-- name: GetAlbumAccessLevel :one
+SELECT coalesce(max(CASE level WHEN 'manage' THEN 3 WHEN 'transform' THEN 2 WHEN 'view' THEN 1 END) , 0)::int AS access_level FROM permissions
+WHERE grantee_id=$1 AND album_id=$2;
+-- name: GetImagesSharedWithUser :many
+SELECT images.* , permissions.level FROM images JOIN permissions ON permissions.image_id=images.image_id
+WHERE permissions.grantee_id=$1 AND images.deleted_at IS NULL ORDER BY permissions.created_at DESC , images.image_id DESC;
+-- name: GetAlbumsSharedWithUser :many
+SELECT albums.* , permissions.level FROM albums JOIN permissions ON permissions.album_id=albums.album_id
+WHERE permissions.grantee_id=$1 ORDER BY permissions.created_at DESC , albums.album_id DESC;
@@ -0,0 +1,14 @@
+-- name: CreateSession :one
+INSERT INTO sessions(session_id , user_id , org_id , refresh_token_hash , user_agent , client_ip , expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING *;
+-- name: GetSession :one
+SELECT * FROM sessions WHERE session_id=$1;
+-- name: RotateSession :one
+-- nothing is updated when the session was blocked or its token already rotated by a concurrent renewal
+UPDATE sessions SET refresh_token_hash=sqlc.arg(new_token_hash) , org_id=sqlc.arg(org_id) , last_used_at=now()
+WHERE session_id=sqlc.arg(session_id) AND refresh_token_hash=sqlc.arg(old_token_hash) AND NOT is_blocked RETURNING *;
+-- name: BlockSession :exec
+UPDATE sessions SET is_blocked=true WHERE session_id=$1;
+-- name: RevokeUserSession :execrows
+UPDATE sessions SET is_blocked=true WHERE session_id=$1 AND user_id=$2 AND NOT is_blocked;
+-- name: GetActiveSessions :many
+SELECT * FROM sessions WHERE user_id=$1 AND NOT is_blocked AND expires_at>now() ORDER BY last_used_at DESC;
@@ -0,0 +1,13 @@
+-- name: CreateShareLink :one
+INSERT INTO share_links(token , user_id , image_id , album_id , password_hash , expires_at , max_views) VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING *;
+-- name: GetShareLinkByToken :one
+SELECT * FROM share_links WHERE token=$1;
+-- name: RecordShareLinkView :one
+-- the view is only counted while the link is usable , no rows means it expired or ran out of views in the meantime
+UPDATE share_links SET view_count=view_count+1 , last_viewed_at=now()
+WHERE share_id=$1 AND (expires_at IS NULL OR expires_at>now()) AND (max_views IS NULL OR view_count<max_views)
+RETURNING *;
+-- name: GetUserShareLinks :many
+SELECT * FROM share_links WHERE user_id=$1 ORDER BY created_at DESC , share_id DESC;
+-- name: DeleteShareLink :execrows
+DELETE FROM share_links WHERE share_id=$1 AND user_id=$2;
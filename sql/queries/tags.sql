@@ -0,0 +1,17 @@
+-- name: UpsertTag :one
+INSERT INTO tags(user_id , org_id , name) VALUES ($1,$2,$3) ON CONFLICT (org_id , name) DO UPDATE SET name=EXCLUDED.name RETURNING *;
+-- name: GetTagByName :one
+SELECT * FROM tags WHERE org_id=$1 AND name=$2;
+-- name: GetOrgTags :many
+SELECT tags.tag_id , tags.name , tags.created_at , count(images.image_id) AS image_count FROM tags
+LEFT JOIN image_tags ON image_tags.tag_id=tags.tag_id
+LEFT JOIN images ON images.image_id=image_tags.image_id AND images.deleted_at IS NULL
+WHERE tags.org_id=$1 GROUP BY tags.tag_id ORDER BY tags.name;
+-- name: GetImageTags :many
+SELECT tags.name FROM tags JOIN image_tags ON image_tags.tag_id=tags.tag_id WHERE image_tags.image_id=$1 ORDER BY tags.name;
+-- name: AddTagToImages :execrows
+-- images of other organizations are skipped
+INSERT INTO image_tags(image_id , tag_id) SELECT image_id , sqlc.arg(tag_id)::bigint FROM images
+WHERE org_id=sqlc.arg(org_id) AND deleted_at IS NULL AND image_id=ANY(sqlc.arg(image_ids)::bigint[]) ON CONFLICT DO NOTHING;
+-- name: RemoveTagFromImages :execrows
+DELETE FROM image_tags WHERE tag_id=$1 AND image_id=ANY($2::bigint[]);
@@ -0,0 +1,27 @@
+-- name: CreateUpload :one
+INSERT INTO uploads(upload_id , user_id , org_id , file_name , upload_length , expires_at) VALUES ($1,$2,$3,$4,$5,$6) RETURNING *;
+-- name: GetUpload :one
+SELECT * FROM uploads WHERE upload_id=$1;
+-- name: AppendUploadChunk :one
+-- the offset check makes concurrent PATCH requests for the same upload fail instead of interleaving
+UPDATE uploads SET upload_offset=upload_offset+sqlc.arg(chunk_size)::bigint , chunks=array_append(chunks , sqlc.arg(chunk)::varchar) , updated_at=now()
+WHERE upload_id=sqlc.arg(upload_id) AND upload_offset=sqlc.arg(upload_offset) AND image_id IS NULL
+RETURNING *;
+-- name: ClaimUpload :execrows
+-- no rows are updated when the upload is finished or another request is still finishing it
+UPDATE uploads SET finishing_at=now() WHERE upload_id=$1 AND image_id IS NULL AND (finishing_at IS NULL OR finishing_at<$2);
+-- name: ReleaseUpload :exec
+-- lets the upload be finished again after finishing it failed
+UPDATE uploads SET finishing_at=NULL WHERE upload_id=$1 AND image_id IS NULL;
+-- name: CompleteUpload :execrows
+UPDATE uploads SET image_id=$2 , chunks='{}' , updated_at=now() WHERE upload_id=$1 AND image_id IS NULL;
+-- name: DeleteUpload :exec
+DELETE FROM uploads WHERE upload_id=$1;
+-- name: GetExpiredUploads :many
+SELECT * FROM uploads WHERE expires_at<$1 ORDER BY expires_at LIMIT $2;
+-- name: ClaimPresignedUpload :execrows
+-- no rows are inserted when the object was already completed
+INSERT INTO presigned_completions(object_name , org_id) VALUES ($1,$2) ON CONFLICT (object_name) DO NOTHING;
+-- name: ReleasePresignedUpload :exec
+-- lets the object be completed again after a completion failed
+DELETE FROM presigned_completions WHERE object_name=$1;
@@ -0,0 +1,21 @@
+-- name: ReplaceImageContent :one
+-- the current content is archived as a version and replaced , nothing happens when the image is no longer at current_version
+WITH archived AS (
+INSERT INTO image_versions(image_id , version , file_name , file_size , storage_url , metadata , content_hash , phash , transformations , created_at)
+SELECT image_id , version , file_name , file_size , storage_url , metadata , content_hash , phash , transformations , version_created_at FROM images
+WHERE image_id=sqlc.arg(image_id) AND version=sqlc.arg(current_version) AND deleted_at IS NULL
+RETURNING image_id
+)
+UPDATE images SET file_name=sqlc.arg(file_name) , file_size=sqlc.arg(file_size) , storage_url=sqlc.arg(storage_url) , metadata=sqlc.arg(metadata) ,
+content_hash=sqlc.arg(content_hash) , phash=sqlc.arg(phash) , transformations=sqlc.arg(transformations) ,
+version=images.version+1 , version_created_at=now() , updated_at=now()
+FROM archived WHERE images.image_id=archived.image_id RETURNING images.*;
+-- name: GetImageVersions :many
+SELECT * FROM image_versions WHERE image_id=$1 ORDER BY version DESC;
+-- name: GetImageVersion :one
+SELECT * FROM image_versions WHERE image_id=$1 AND version=$2;
+-- name: DeleteOldImageVersions :many
+-- only the newest keep versions of the image are kept , the deleted ones are returned so their content can be released
+DELETE FROM image_versions WHERE image_id=sqlc.arg(image_id) AND version_id NOT IN (
+SELECT version_id FROM image_versions WHERE image_id=sqlc.arg(image_id) ORDER BY version DESC LIMIT sqlc.arg(keep)
+) RETURNING *;
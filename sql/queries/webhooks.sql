@@ -0,0 +1,28 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks(user_id , url , secret , events) VALUES ($1,$2,$3,$4) RETURNING *;
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE webhook_id=$1;
+-- name: GetUserWebhooks :many
+SELECT * FROM webhooks WHERE user_id=$1 ORDER BY created_at DESC;
+-- name: GetSubscribedWebhooks :many
+SELECT * FROM webhooks WHERE user_id=$1 AND active AND @event::varchar = ANY(events);
+-- name: DeleteUserWebhook :exec
+DELETE FROM webhooks WHERE webhook_id=$1 AND user_id=$2;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries(webhook_id , event , payload) VALUES ($1,$2,$3) RETURNING *;
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE delivery_id=$1;
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries WHERE webhook_id=$1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+-- name: ClaimWebhookDelivery :one
+-- claiming pushes next_attempt_at forward so that a delivery left behind by a crashed dispatcher is picked up again once the lease expires
+UPDATE webhook_deliveries SET attempts=attempts+1 , next_attempt_at=$1
+WHERE delivery_id=(SELECT delivery_id FROM webhook_deliveries WHERE status='pending' AND next_attempt_at<=now() ORDER BY next_attempt_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+RETURNING *;
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries SET status='delivered' , response_status=$2 , last_error=NULL , delivered_at=now() WHERE delivery_id=$1;
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries SET response_status=$2 , last_error=$3 , next_attempt_at=$4 WHERE delivery_id=$1;
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries SET status='failed' , response_status=$2 , last_error=$3 WHERE delivery_id=$1;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS jobs (
+job_id bigserial PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+image_id bigint NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
+spec jsonb NOT NULL,
+status varchar NOT NULL DEFAULT 'pending',
+attempts int NOT NULL DEFAULT 0,
+max_attempts int NOT NULL DEFAULT 5,
+last_error varchar,
+result_image_id bigint REFERENCES images(image_id) ON DELETE SET NULL,
+run_at timestamptz NOT NULL DEFAULT (now()),
+created_at timestamptz NOT NULL DEFAULT (now()),
+updated_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON jobs(status, run_at);
+CREATE INDEX ON jobs(user_id);
+
+-- +goose Down
+DROP TABLE jobs;
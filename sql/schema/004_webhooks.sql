@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhooks (
+webhook_id bigserial PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+url varchar NOT NULL,
+secret varchar NOT NULL,
+events varchar[] NOT NULL,
+active boolean NOT NULL DEFAULT true,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON webhooks(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+delivery_id bigserial PRIMARY KEY,
+webhook_id bigint NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+event varchar NOT NULL,
+payload jsonb NOT NULL,
+status varchar NOT NULL DEFAULT 'pending',
+attempts int NOT NULL DEFAULT 0,
+response_status int,
+last_error varchar,
+next_attempt_at timestamptz NOT NULL DEFAULT (now()),
+delivered_at timestamptz,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX ON webhook_deliveries(webhook_id);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS uploads (
+upload_id varchar PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+file_name varchar NOT NULL,
+upload_length bigint NOT NULL,
+upload_offset bigint NOT NULL DEFAULT 0,
+chunks varchar[] NOT NULL DEFAULT '{}',
+image_id bigint REFERENCES images(image_id) ON DELETE SET NULL,
+expires_at timestamptz NOT NULL,
+created_at timestamptz NOT NULL DEFAULT (now()),
+updated_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON uploads(expires_at);
+
+-- +goose Down
+DROP TABLE uploads;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blobs (
+content_hash varchar PRIMARY KEY,
+file_name varchar NOT NULL,
+file_size bigint NOT NULL,
+storage_url varchar NOT NULL,
+ref_count int NOT NULL DEFAULT 0,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+ALTER TABLE images ADD COLUMN content_hash varchar REFERENCES blobs(content_hash);
+CREATE INDEX ON images(user_id, content_hash);
+
+-- +goose Down
+ALTER TABLE images DROP COLUMN content_hash;
+DROP TABLE blobs;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX images_user_created_at_idx ON images(user_id, created_at);
+CREATE INDEX images_user_file_size_idx ON images(user_id, file_size);
+CREATE INDEX images_user_file_name_idx ON images(user_id, file_name);
+CREATE INDEX images_user_content_type_idx ON images(user_id, (metadata->>'content_type'));
+-- substring searches on the file name
+CREATE INDEX images_file_name_trgm_idx ON images USING gin (file_name gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX images_file_name_trgm_idx;
+DROP INDEX images_user_content_type_idx;
+DROP INDEX images_user_file_name_idx;
+DROP INDEX images_user_file_size_idx;
+DROP INDEX images_user_created_at_idx;
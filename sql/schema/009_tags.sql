@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tags (
+tag_id bigserial PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+name varchar NOT NULL,
+created_at timestamptz NOT NULL DEFAULT (now()),
+UNIQUE (user_id, name)
+);
+CREATE TABLE IF NOT EXISTS image_tags (
+image_id bigint NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
+tag_id bigint NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+PRIMARY KEY (image_id, tag_id)
+);
+CREATE INDEX ON image_tags(tag_id);
+-- user defined key/value pairs , the extracted metadata stays in the metadata column
+ALTER TABLE images ADD COLUMN attributes jsonb NOT NULL DEFAULT '{}';
+CREATE INDEX images_attributes_idx ON images USING gin (attributes jsonb_path_ops);
+
+-- +goose Down
+DROP INDEX images_attributes_idx;
+ALTER TABLE images DROP COLUMN attributes;
+DROP TABLE image_tags;
+DROP TABLE tags;
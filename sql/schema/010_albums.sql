@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS albums (
+album_id bigserial PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+title varchar NOT NULL,
+description varchar NOT NULL DEFAULT '',
+cover_image_id bigint REFERENCES images(image_id) ON DELETE SET NULL,
+created_at timestamptz NOT NULL DEFAULT (now()),
+updated_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON albums(user_id);
+CREATE TABLE IF NOT EXISTS album_images (
+album_id bigint NOT NULL REFERENCES albums(album_id) ON DELETE CASCADE,
+image_id bigint NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
+position int NOT NULL,
+added_at timestamptz NOT NULL DEFAULT (now()),
+PRIMARY KEY (album_id, image_id)
+);
+CREATE INDEX ON album_images(album_id, position);
+CREATE INDEX ON album_images(image_id);
+
+-- +goose Down
+DROP TABLE album_images;
+DROP TABLE albums;
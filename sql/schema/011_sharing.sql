@@ -0,0 +1,24 @@
+-- +goose Up
+-- private images are only visible to their owner , unlisted ones to anyone with the id and public ones are also listed
+ALTER TABLE images ADD COLUMN visibility varchar NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'unlisted', 'public'));
+CREATE INDEX images_public_idx ON images(created_at) WHERE visibility='public';
+CREATE TABLE IF NOT EXISTS share_links (
+share_id bigserial PRIMARY KEY,
+token varchar NOT NULL UNIQUE,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+image_id bigint REFERENCES images(image_id) ON DELETE CASCADE,
+album_id bigint REFERENCES albums(album_id) ON DELETE CASCADE,
+password_hash varchar,
+expires_at timestamptz,
+max_views int,
+view_count int NOT NULL DEFAULT 0,
+created_at timestamptz NOT NULL DEFAULT (now()),
+-- a link shares either an image or an album
+CHECK ((image_id IS NULL) <> (album_id IS NULL))
+);
+CREATE INDEX ON share_links(user_id);
+
+-- +goose Down
+DROP TABLE share_links;
+DROP INDEX images_public_idx;
+ALTER TABLE images DROP COLUMN visibility;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- grants other users access to an image or an album , a grant on an album covers its images
+CREATE TABLE IF NOT EXISTS permissions (
+permission_id bigserial PRIMARY KEY,
+image_id bigint REFERENCES images(image_id) ON DELETE CASCADE,
+album_id bigint REFERENCES albums(album_id) ON DELETE CASCADE,
+grantee_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+granted_by bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+level varchar NOT NULL CHECK (level IN ('view', 'transform', 'manage')),
+created_at timestamptz NOT NULL DEFAULT (now()),
+CHECK ((image_id IS NULL) <> (album_id IS NULL))
+);
+CREATE UNIQUE INDEX permissions_image_grantee_idx ON permissions(image_id, grantee_id) WHERE image_id IS NOT NULL;
+CREATE UNIQUE INDEX permissions_album_grantee_idx ON permissions(album_id, grantee_id) WHERE album_id IS NOT NULL;
+CREATE INDEX ON permissions(grantee_id);
+
+-- +goose Down
+DROP TABLE permissions;
@@ -0,0 +1,55 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS organizations (
+org_id bigserial PRIMARY KEY,
+name varchar NOT NULL,
+-- set for the workspace every user gets when they register , it can't be deleted and the user can't leave it
+personal_user_id bigint UNIQUE REFERENCES users(user_id) ON DELETE CASCADE,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE TABLE IF NOT EXISTS org_members (
+org_id bigint NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+role varchar NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+created_at timestamptz NOT NULL DEFAULT (now()),
+PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX ON org_members(user_id);
+
+-- the existing libraries move to the personal organization of their user
+INSERT INTO organizations(name, personal_user_id) SELECT full_name, user_id FROM users;
+INSERT INTO org_members(org_id, user_id, role) SELECT org_id, personal_user_id, 'owner' FROM organizations;
+
+-- images keep user_id as the uploader , org_id is the tenant that owns them
+ALTER TABLE images ADD COLUMN org_id bigint REFERENCES organizations(org_id);
+UPDATE images SET org_id=organizations.org_id FROM organizations WHERE organizations.personal_user_id=images.user_id;
+ALTER TABLE images ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX images_org_created_at_idx ON images(org_id, created_at);
+CREATE INDEX images_org_file_size_idx ON images(org_id, file_size);
+CREATE INDEX images_org_file_name_idx ON images(org_id, file_name);
+CREATE INDEX images_org_content_type_idx ON images(org_id, (metadata->>'content_type'));
+CREATE INDEX ON images(org_id, content_hash);
+
+ALTER TABLE albums ADD COLUMN org_id bigint REFERENCES organizations(org_id) ON DELETE CASCADE;
+UPDATE albums SET org_id=organizations.org_id FROM organizations WHERE organizations.personal_user_id=albums.user_id;
+ALTER TABLE albums ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX ON albums(org_id);
+
+ALTER TABLE tags ADD COLUMN org_id bigint REFERENCES organizations(org_id) ON DELETE CASCADE;
+UPDATE tags SET org_id=organizations.org_id FROM organizations WHERE organizations.personal_user_id=tags.user_id;
+ALTER TABLE tags ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE tags DROP CONSTRAINT tags_user_id_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_org_id_name_key UNIQUE (org_id, name);
+
+ALTER TABLE uploads ADD COLUMN org_id bigint REFERENCES organizations(org_id) ON DELETE CASCADE;
+UPDATE uploads SET org_id=organizations.org_id FROM organizations WHERE organizations.personal_user_id=uploads.user_id;
+ALTER TABLE uploads ALTER COLUMN org_id SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE uploads DROP COLUMN org_id;
+ALTER TABLE tags DROP CONSTRAINT tags_org_id_name_key;
+ALTER TABLE tags DROP COLUMN org_id;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+ALTER TABLE albums DROP COLUMN org_id;
+ALTER TABLE images DROP COLUMN org_id;
+DROP TABLE org_members;
+DROP TABLE organizations;
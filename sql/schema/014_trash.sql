@@ -0,0 +1,10 @@
+-- +goose Up
+-- deleted images stay in the trash until they are restored or the retention period runs out
+ALTER TABLE images ADD COLUMN deleted_at timestamptz;
+CREATE INDEX images_trash_idx ON images(org_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX images_deleted_at_idx ON images(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX images_deleted_at_idx;
+DROP INDEX images_trash_idx;
+ALTER TABLE images DROP COLUMN deleted_at;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- the images row always holds the current version , replaced content is archived in image_versions
+ALTER TABLE images ADD COLUMN version int NOT NULL DEFAULT 1;
+ALTER TABLE images ADD COLUMN version_created_at timestamptz NOT NULL DEFAULT (now());
+-- the transformations that produced the current version , null when it was uploaded
+ALTER TABLE images ADD COLUMN transformations jsonb;
+UPDATE images SET version_created_at=created_at;
+CREATE TABLE IF NOT EXISTS image_versions (
+version_id bigserial PRIMARY KEY,
+image_id bigint NOT NULL REFERENCES images(image_id) ON DELETE CASCADE,
+version int NOT NULL,
+file_name varchar NOT NULL,
+file_size bigint NOT NULL,
+storage_url varchar NOT NULL,
+metadata jsonb,
+content_hash varchar,
+phash bigint,
+transformations jsonb,
+created_at timestamptz NOT NULL,
+archived_at timestamptz NOT NULL DEFAULT (now()),
+UNIQUE (image_id, version)
+);
+
+-- +goose Down
+DROP TABLE image_versions;
+ALTER TABLE images DROP COLUMN transformations;
+ALTER TABLE images DROP COLUMN version_created_at;
+ALTER TABLE images DROP COLUMN version;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- every login starts a session , its refresh token is rotated on every renewal and only the hash of the current one is stored
+CREATE TABLE IF NOT EXISTS sessions (
+session_id varchar PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+org_id bigint NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+refresh_token_hash varchar NOT NULL,
+user_agent varchar NOT NULL,
+client_ip varchar NOT NULL,
+is_blocked boolean NOT NULL DEFAULT false,
+expires_at timestamptz NOT NULL,
+created_at timestamptz NOT NULL DEFAULT (now()),
+last_used_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON sessions(user_id);
+
+-- +goose Down
+DROP TABLE sessions;
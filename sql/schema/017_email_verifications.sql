@@ -0,0 +1,14 @@
+-- +goose Up
+-- a verification is a one-time token emailed to the user , only its hash is stored
+CREATE TABLE IF NOT EXISTS email_verifications (
+token_hash varchar PRIMARY KEY,
+user_id bigint NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+email varchar NOT NULL,
+expires_at timestamptz NOT NULL,
+used_at timestamptz,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+CREATE INDEX ON email_verifications(user_id , created_at);
+
+-- +goose Down
+DROP TABLE email_verifications;
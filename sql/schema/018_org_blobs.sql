@@ -0,0 +1,44 @@
+-- +goose Up
+-- blobs are scoped to the organization that owns them so content is only shared inside an organization ,
+-- content that was already shared across organizations gets a blob per organization that keeps pointing at the existing object
+CREATE TABLE IF NOT EXISTS org_blobs (
+org_id bigint NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+content_hash varchar NOT NULL,
+file_name varchar NOT NULL,
+file_size bigint NOT NULL,
+storage_url varchar NOT NULL,
+ref_count int NOT NULL DEFAULT 0,
+created_at timestamptz NOT NULL DEFAULT (now()),
+PRIMARY KEY (org_id, content_hash)
+);
+INSERT INTO org_blobs(org_id , content_hash , file_name , file_size , storage_url , ref_count , created_at)
+SELECT refs.org_id , blobs.content_hash , blobs.file_name , blobs.file_size , blobs.storage_url , count(*) , blobs.created_at
+FROM (
+SELECT org_id , content_hash FROM images WHERE content_hash IS NOT NULL
+UNION ALL
+SELECT images.org_id , image_versions.content_hash FROM image_versions JOIN images ON images.image_id=image_versions.image_id WHERE image_versions.content_hash IS NOT NULL
+) refs
+JOIN blobs ON blobs.content_hash=refs.content_hash
+GROUP BY refs.org_id , blobs.content_hash;
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_content_hash_fkey;
+DROP TABLE blobs;
+ALTER TABLE org_blobs RENAME TO blobs;
+ALTER TABLE images ADD FOREIGN KEY (org_id, content_hash) REFERENCES blobs(org_id, content_hash);
+CREATE INDEX ON blobs(file_name);
+
+-- +goose Down
+ALTER TABLE images DROP CONSTRAINT IF EXISTS images_org_id_content_hash_fkey;
+CREATE TABLE IF NOT EXISTS global_blobs (
+content_hash varchar PRIMARY KEY,
+file_name varchar NOT NULL,
+file_size bigint NOT NULL,
+storage_url varchar NOT NULL,
+ref_count int NOT NULL DEFAULT 0,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+INSERT INTO global_blobs(content_hash , file_name , file_size , storage_url , ref_count , created_at)
+SELECT DISTINCT ON (content_hash) content_hash , file_name , file_size , storage_url , sum(ref_count) OVER (PARTITION BY content_hash) , created_at
+FROM blobs ORDER BY content_hash , created_at;
+DROP TABLE blobs;
+ALTER TABLE global_blobs RENAME TO blobs;
+ALTER TABLE images ADD FOREIGN KEY (content_hash) REFERENCES blobs(content_hash);
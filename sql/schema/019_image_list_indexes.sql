@@ -0,0 +1,27 @@
+-- +goose Up
+-- the list is filtered by organization since 013 , the per-user indexes are no longer used
+DROP INDEX IF EXISTS images_user_created_at_idx;
+DROP INDEX IF EXISTS images_user_file_size_idx;
+DROP INDEX IF EXISTS images_user_file_name_idx;
+DROP INDEX IF EXISTS images_user_content_type_idx;
+DROP INDEX IF EXISTS images_user_id_content_hash_idx;
+-- the image id breaks ties in every sort , ending the indexes with it lets them serve both the order by and the keyset cursor of the list
+DROP INDEX IF EXISTS images_org_created_at_idx;
+DROP INDEX IF EXISTS images_org_file_size_idx;
+DROP INDEX IF EXISTS images_org_file_name_idx;
+CREATE INDEX images_org_created_at_idx ON images(org_id, created_at, image_id) WHERE deleted_at IS NULL;
+CREATE INDEX images_org_file_size_idx ON images(org_id, file_size, image_id) WHERE deleted_at IS NULL;
+CREATE INDEX images_org_file_name_idx ON images(org_id, file_name, image_id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX images_org_file_name_idx;
+DROP INDEX images_org_file_size_idx;
+DROP INDEX images_org_created_at_idx;
+CREATE INDEX images_org_created_at_idx ON images(org_id, created_at);
+CREATE INDEX images_org_file_size_idx ON images(org_id, file_size);
+CREATE INDEX images_org_file_name_idx ON images(org_id, file_name);
+CREATE INDEX ON images(user_id, content_hash);
+CREATE INDEX images_user_content_type_idx ON images(user_id, (metadata->>'content_type'));
+CREATE INDEX images_user_file_name_idx ON images(user_id, file_name);
+CREATE INDEX images_user_file_size_idx ON images(user_id, file_size);
+CREATE INDEX images_user_created_at_idx ON images(user_id, created_at);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- a presigned object can only be completed once , the insert claims it so concurrent completions can't both create an image
+CREATE TABLE IF NOT EXISTS presigned_completions (
+object_name varchar PRIMARY KEY,
+org_id bigint NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+created_at timestamptz NOT NULL DEFAULT (now())
+);
+-- objects completed before the table existed
+INSERT INTO presigned_completions(object_name , org_id)
+SELECT DISTINCT file_name , org_id FROM images WHERE file_name LIKE 'orgs/%/presigned/%'
+ON CONFLICT (object_name) DO NOTHING;
+
+-- +goose Down
+DROP TABLE presigned_completions;
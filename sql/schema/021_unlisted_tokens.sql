@@ -0,0 +1,8 @@
+-- +goose Up
+-- unlisted images are served by a random token instead of their id so that they can't be found by walking the ids
+ALTER TABLE images ADD COLUMN unlisted_token varchar UNIQUE;
+UPDATE images SET unlisted_token=replace(gen_random_uuid()::text , '-' , '') || replace(gen_random_uuid()::text , '-' , '') WHERE visibility='unlisted';
+ALTER TABLE images ADD CHECK ((visibility='unlisted') = (unlisted_token IS NOT NULL));
+
+-- +goose Down
+ALTER TABLE images DROP COLUMN unlisted_token;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- the raw routes of a link don't count views , they keep working for a while after the last counted view
+ALTER TABLE share_links ADD COLUMN last_viewed_at timestamptz;
+
+-- +goose Down
+ALTER TABLE share_links DROP COLUMN last_viewed_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- the request that turns a finished upload into an image claims it first , the claim expires so a crashed request can be retried
+ALTER TABLE uploads ADD COLUMN finishing_at timestamptz;
+
+-- +goose Down
+ALTER TABLE uploads DROP COLUMN finishing_at;
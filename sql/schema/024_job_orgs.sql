@@ -0,0 +1,9 @@
+-- +goose Up
+-- the output of a job is saved in the organization it was queued in , the user is a member of it
+ALTER TABLE jobs ADD COLUMN org_id bigint REFERENCES organizations(org_id) ON DELETE CASCADE;
+-- the user isn't always a member of the organization of the source image , queued jobs save into their personal organization
+UPDATE jobs SET org_id=organizations.org_id FROM organizations WHERE organizations.personal_user_id=jobs.user_id;
+ALTER TABLE jobs ALTER COLUMN org_id SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE jobs DROP COLUMN org_id;